@@ -0,0 +1,379 @@
+package api
+
+import (
+	"bytes"
+	"cli_todo_application/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Error is a failure reported by the server. It unwraps to the matching
+// storage error, so errors.Is works the same as against a local store.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case codeNotFound, codeInvalidReference:
+		return storage.ErrTaskNotFound
+	case codeAmbiguous:
+		return storage.ErrAmbiguousTask
+	case codeAlreadyCompleted:
+		return storage.ErrAlreadyCompleted
+	case codeOpenSubtasks:
+		return storage.ErrOpenSubtasks
+	case codeInvalidSelection:
+		return storage.ErrInvalidSelection
+	case codeDependencyLoop:
+		return storage.ErrDependencyLoop
+	case codeAlreadyRunning:
+		return storage.ErrAlreadyRunning
+	case codeNoRunningTask:
+		return storage.ErrNoRunningTask
+	case codeNothingToUndo:
+		return storage.ErrNothingToUndo
+	case codeNothingToRedo:
+		return storage.ErrNothingToRedo
+	case codeTaskChanged:
+		return storage.ErrTaskChanged
+	}
+	return nil
+}
+
+// Client talks to a server started by "task serve". Its methods mirror the
+// TaskStore methods of the same name.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient returns a client for the server listening on addr.
+func NewClient(addr, token string) *Client {
+	return &Client{
+		baseURL: "http://" + addr,
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) AddTask(template storage.Task) (*storage.Task, error) {
+	var task storage.Task
+	if err := c.do(http.MethodPost, "/tasks", nil, template, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) GetTask(id int) (*storage.Task, error) {
+	return c.ResolveTask(strconv.Itoa(id))
+}
+
+func (c *Client) ResolveTask(ref string) (*storage.Task, error) {
+	var task storage.Task
+	if err := c.do(http.MethodGet, "/tasks/"+url.PathEscape(ref), nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) TaskAtPosition(project string, position int) (*storage.Task, error) {
+	incompleteTasks, err := c.GetProjectTasks(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incomplete tasks: %w", err)
+	}
+
+	if position < 1 || position > len(incompleteTasks) {
+		return nil, fmt.Errorf("invalid task number: %d (must be between 1 and %d)", position, len(incompleteTasks))
+	}
+
+	task := incompleteTasks[position-1]
+	return &task, nil
+}
+
+func (c *Client) GetIncompleteTasks() ([]storage.Task, error) {
+	var tasks []storage.Task
+	err := c.do(http.MethodGet, "/tasks", url.Values{"all": {"true"}}, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) GetProjectTasks(project string) ([]storage.Task, error) {
+	var tasks []storage.Task
+	err := c.do(http.MethodGet, "/tasks", url.Values{"project": {project}}, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) CompleteTask(id int, force bool) (*storage.Task, error) {
+	var task storage.Task
+	query := url.Values{"force": {strconv.FormatBool(force)}}
+	if err := c.do(http.MethodPost, fmt.Sprintf("/tasks/%d/complete", id), query, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) DeleteTask(id int) (*storage.Task, error) {
+	var task storage.Task
+	if err := c.do(http.MethodDelete, fmt.Sprintf("/tasks/%d", id), nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) CompleteTasks(sel storage.Selection, force bool) ([]storage.Task, error) {
+	var tasks []storage.Task
+	query := url.Values{"force": {strconv.FormatBool(force)}}
+	err := c.do(http.MethodPost, "/tasks/complete", query, sel, &tasks)
+	return tasks, err
+}
+
+func (c *Client) DeleteTasks(sel storage.Selection) ([]storage.Task, error) {
+	var tasks []storage.Task
+	err := c.do(http.MethodPost, "/tasks/delete", nil, sel, &tasks)
+	return tasks, err
+}
+
+func (c *Client) GetCompletedTasks(from, to time.Time) ([]storage.Task, error) {
+	query := url.Values{}
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339))
+	}
+
+	var tasks []storage.Task
+	err := c.do(http.MethodGet, "/completed", query, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) EditTask(id int, edit storage.TaskEdit) (*storage.Task, error) {
+	return c.taskRequest(http.MethodPatch, fmt.Sprintf("/tasks/%d", id), nil, edit)
+}
+
+func (c *Client) MoveTask(id int, project string) (*storage.Task, error) {
+	return c.taskRequest(http.MethodPost, fmt.Sprintf("/tasks/%d/move", id), nil, projectResponse{Project: project})
+}
+
+func (c *Client) AddDependency(id, blockerID int) (*storage.Task, error) {
+	return c.taskRequest(http.MethodPost, fmt.Sprintf("/tasks/%d/blockers", id), nil, blockerRequest{ID: blockerID})
+}
+
+func (c *Client) RemoveDependency(id, blockerID int) (*storage.Task, error) {
+	return c.taskRequest(http.MethodDelete, fmt.Sprintf("/tasks/%d/blockers/%d", id, blockerID), nil, nil)
+}
+
+func (c *Client) StartTask(id int) (task, stopped *storage.Task, err error) {
+	var response startResponse
+	if err := c.do(http.MethodPost, fmt.Sprintf("/tasks/%d/start", id), nil, nil, &response); err != nil {
+		return nil, nil, err
+	}
+	return response.Task, response.Stopped, nil
+}
+
+func (c *Client) StopTask() (*storage.Task, error) {
+	return c.taskRequest(http.MethodPost, "/timer/stop", nil, nil)
+}
+
+func (c *Client) NextTasks(project string, limit int) ([]storage.Task, error) {
+	var tasks []storage.Task
+	query := url.Values{"project": {project}, "limit": {strconv.Itoa(limit)}}
+	err := c.do(http.MethodGet, "/next", query, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) SearchTasks(pattern string, regex bool) ([]storage.Task, error) {
+	var tasks []storage.Task
+	query := url.Values{"q": {pattern}, "regex": {strconv.FormatBool(regex)}}
+	err := c.do(http.MethodGet, "/search", query, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) GetAllTasks() ([]storage.Task, error) {
+	var tasks []storage.Task
+	err := c.do(http.MethodGet, "/export", nil, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) GetArchivedTasks() ([]storage.Task, error) {
+	var tasks []storage.Task
+	err := c.do(http.MethodGet, "/archive", nil, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) ArchiveTasks(cutoff time.Time, dryRun bool) ([]storage.Task, error) {
+	var tasks []storage.Task
+	query := url.Values{"before": {cutoff.Format(time.RFC3339)}, "dry_run": {strconv.FormatBool(dryRun)}}
+	err := c.do(http.MethodPost, "/archive", query, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) PurgeArchive(cutoff time.Time, dryRun bool) ([]storage.Task, error) {
+	var tasks []storage.Task
+	query := url.Values{"dry_run": {strconv.FormatBool(dryRun)}}
+	if !cutoff.IsZero() {
+		query.Set("before", cutoff.Format(time.RFC3339))
+	}
+	err := c.do(http.MethodPost, "/archive/purge", query, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) ImportTasks(tasks []storage.Task, dryRun bool) (*storage.ImportResult, error) {
+	var result storage.ImportResult
+	query := url.Values{"dry_run": {strconv.FormatBool(dryRun)}}
+	if err := c.do(http.MethodPost, "/import", query, nonNil(tasks), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) Undo() (*storage.JournalEntry, error) {
+	var entry storage.JournalEntry
+	if err := c.do(http.MethodPost, "/undo", nil, nil, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (c *Client) Redo() (*storage.JournalEntry, error) {
+	var entry storage.JournalEntry
+	if err := c.do(http.MethodPost, "/redo", nil, nil, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (c *Client) GetJournal(limit int) ([]storage.JournalEntry, error) {
+	var entries []storage.JournalEntry
+	err := c.do(http.MethodGet, "/journal", url.Values{"limit": {strconv.Itoa(limit)}}, nil, &entries)
+	return entries, err
+}
+
+func (c *Client) TrimJournal(keep int) (int, error) {
+	var response trimResponse
+	err := c.do(http.MethodPost, "/journal/trim", url.Values{"keep": {strconv.Itoa(keep)}}, nil, &response)
+	return response.Removed, err
+}
+
+func (c *Client) GetProjects() ([]storage.ProjectInfo, error) {
+	var projects []storage.ProjectInfo
+	err := c.do(http.MethodGet, "/projects", nil, nil, &projects)
+	return projects, err
+}
+
+func (c *Client) DefaultProject() (string, error) {
+	var response projectResponse
+	err := c.do(http.MethodGet, "/projects/default", nil, nil, &response)
+	return response.Project, err
+}
+
+func (c *Client) SetDefaultProject(project string) error {
+	return c.do(http.MethodPut, "/projects/default", nil, projectResponse{Project: project}, nil)
+}
+
+func (c *Client) SyncDir() (string, error) {
+	var response syncDirBody
+	err := c.do(http.MethodGet, "/sync/dir", nil, nil, &response)
+	return response.Dir, err
+}
+
+func (c *Client) SetSyncDir(dir string) error {
+	return c.do(http.MethodPut, "/sync/dir", nil, syncDirBody{Dir: dir}, nil)
+}
+
+func (c *Client) Sync(dir string) (*storage.SyncResult, error) {
+	var result storage.SyncResult
+	if err := c.do(http.MethodPost, "/sync", nil, syncDirBody{Dir: dir}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// taskRequest sends a request whose response is a single task.
+func (c *Client) taskRequest(method, path string, query url.Values, body any) (*storage.Task, error) {
+	var task storage.Task
+	if err := c.do(method, path, query, body, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Close is a no-op; it lets a Client stand in for a TaskStore.
+func (c *Client) Close() error {
+	return nil
+}
+
+// ping checks that the server is up and accepts the client's token.
+func (c *Client) ping(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.doContext(ctx, http.MethodGet, "/health", nil, nil, nil)
+}
+
+func (c *Client) do(method, path string, query url.Values, body, out any) error {
+	return c.doContext(context.Background(), method, path, query, body, out)
+}
+
+// doContext sends a request with an optional JSON body and decodes a JSON
+// response into out. Error responses are returned as *Error.
+func (c *Client) doContext(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach task server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var response errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil || response.Error == "" {
+			return &Error{Status: resp.StatusCode, Message: fmt.Sprintf("task server returned %s", resp.Status)}
+		}
+		return &Error{Status: resp.StatusCode, Code: response.Code, Message: response.Error}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
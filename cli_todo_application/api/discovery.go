@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ServerInfo describes a running server. "task serve" writes it next to the
+// database so that other commands using the same database can find it.
+type ServerInfo struct {
+	Addr  string `json:"addr"`
+	Token string `json:"token,omitempty"`
+	PID   int    `json:"pid"`
+}
+
+// InfoPath returns where the server info for the database at dbPath is kept.
+func InfoPath(dbPath string) string {
+	return dbPath + ".server"
+}
+
+// WriteInfo records info for the database at dbPath. The file may contain the
+// bearer token, so it is only readable by its owner, like the database.
+func WriteInfo(dbPath string, info ServerInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal server info: %w", err)
+	}
+	return os.WriteFile(InfoPath(dbPath), data, 0600)
+}
+
+// RemoveInfo deletes the server info for the database at dbPath.
+func RemoveInfo(dbPath string) error {
+	err := os.Remove(InfoPath(dbPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Discover returns a client for the server that holds the database at dbPath,
+// or false when no server is running. Info left behind by a server that
+// exited without cleaning up is ignored because the server does not answer.
+func Discover(dbPath string) (*Client, bool) {
+	data, err := os.ReadFile(InfoPath(dbPath))
+	if err != nil {
+		return nil, false
+	}
+
+	var info ServerInfo
+	if err := json.Unmarshal(data, &info); err != nil || info.Addr == "" {
+		return nil, false
+	}
+
+	client := NewClient(info.Addr, info.Token)
+	if err := client.ping(500 * time.Millisecond); err != nil {
+		return nil, false
+	}
+	return client, true
+}
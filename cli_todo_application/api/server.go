@@ -0,0 +1,865 @@
+// Package api serves the task store as a JSON API on localhost and provides a
+// client for it, so that other programs can use the tasks while the server
+// holds the database lock.
+package api
+
+import (
+	"cli_todo_application/storage"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenEnvVar names the environment variable holding the bearer token that
+// "task serve" requires. Without it the server generates one; see NewToken.
+const TokenEnvVar = "TASK_API_TOKEN"
+
+// Error codes sent alongside error messages so that clients can tell the
+// failures apart without parsing the message.
+const (
+	codeBadRequest       = "bad_request"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeUnsupportedType  = "unsupported_media_type"
+	codeNotFound         = "not_found"
+	codeAmbiguous        = "ambiguous"
+	codeAlreadyCompleted = "already_completed"
+	codeOpenSubtasks     = "open_subtasks"
+	codeInvalidReference = "invalid_reference"
+	codeInvalidSelection = "invalid_selection"
+	codeDependencyLoop   = "dependency_loop"
+	codeAlreadyRunning   = "already_running"
+	codeNoRunningTask    = "no_running_task"
+	codeNothingToUndo    = "nothing_to_undo"
+	codeNothingToRedo    = "nothing_to_redo"
+	codeTaskChanged      = "task_changed"
+	codeInternal         = "internal"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+type projectResponse struct {
+	Project string `json:"project"`
+}
+
+type blockerRequest struct {
+	ID int `json:"id"`
+}
+
+type startResponse struct {
+	Task    *storage.Task `json:"task"`
+	Stopped *storage.Task `json:"stopped,omitempty"`
+}
+
+type trimResponse struct {
+	Removed int `json:"removed"`
+}
+
+type syncDirBody struct {
+	Dir string `json:"dir"`
+}
+
+// Server handles API requests against a single store. The routes are:
+//
+//	GET    /health                  liveness check
+//	GET    /tasks                   incomplete tasks in ?project= (default project
+//	                                when omitted), or in every project with ?all=true
+//	POST   /tasks                   add a task from a JSON task body
+//	GET    /tasks/{ref}             a task by ID or unique description prefix
+//	POST   /tasks/{id}/complete     complete a task; ?force=true completes subtasks
+//	DELETE /tasks/{id}              delete a task
+//	POST   /tasks/complete          complete the tasks in a JSON selection body in
+//	                                one transaction; ?force=true completes subtasks
+//	POST   /tasks/delete            delete the tasks in a JSON selection body in
+//	                                one transaction
+//	PATCH  /tasks/{id}              edit a task with a JSON storage.TaskEdit body
+//	POST   /tasks/{id}/move         move a task to the project in a {"project"} body
+//	POST   /tasks/{id}/blockers     block a task on the task in an {"id"} body
+//	DELETE /tasks/{id}/blockers/{blocker}
+//	                                remove a blocker
+//	POST   /tasks/{id}/start        start the timer on a task
+//	POST   /timer/stop              stop the running timer
+//	GET    /next                    tasks to work on in ?project=, at most ?limit=
+//	GET    /search                  tasks matching ?q=, a regexp with ?regex=true
+//	GET    /completed               tasks completed in [?from, ?to) (RFC 3339)
+//	GET    /export                  every task that is not archived
+//	GET    /archive                 archived tasks
+//	POST   /archive                 archive tasks completed before ?before=
+//	                                (RFC 3339); ?dry_run=true only lists them
+//	POST   /archive/purge           delete archived tasks completed before
+//	                                ?before= (all when omitted); ?dry_run=true
+//	POST   /import                  import a JSON array of tasks; ?dry_run=true
+//	POST   /undo                    undo the last change
+//	POST   /redo                    redo the last undone change
+//	GET    /journal                 the newest ?limit= journal entries
+//	POST   /journal/trim            keep only the newest ?keep= journal entries
+//	GET    /projects                every project with its task counts
+//	GET    /projects/default        the default project
+//	PUT    /projects/default        set the default project from a {"project"} body
+//	GET    /sync/dir                the remembered sync directory
+//	PUT    /sync/dir                remember the sync directory in a {"dir"} body
+//	POST   /sync                    sync through the directory in a {"dir"} body
+type Server struct {
+	store *storage.TaskStore
+	token string
+	mux   *http.ServeMux
+}
+
+// NewServer returns a server for store. When token is not empty every request
+// must carry it as an "Authorization: Bearer" header.
+//
+// Whatever the token, requests must name a loopback host and must not come
+// from another site's page, and request bodies must be sent as
+// application/json. Together these keep web pages in the user's browser from
+// reaching the API, directly or through DNS rebinding.
+func NewServer(store *storage.TaskStore, token string) *Server {
+	s := &Server{store: store, token: token, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /tasks", s.handleList)
+	s.mux.HandleFunc("POST /tasks", s.handleAdd)
+	s.mux.HandleFunc("GET /tasks/{ref}", s.handleGet)
+	s.mux.HandleFunc("POST /tasks/{id}/complete", s.handleComplete)
+	s.mux.HandleFunc("DELETE /tasks/{id}", s.handleDelete)
+	s.mux.HandleFunc("POST /tasks/complete", s.handleCompleteBatch)
+	s.mux.HandleFunc("POST /tasks/delete", s.handleDeleteBatch)
+	s.mux.HandleFunc("PATCH /tasks/{id}", s.handleEdit)
+	s.mux.HandleFunc("POST /tasks/{id}/move", s.handleMove)
+	s.mux.HandleFunc("POST /tasks/{id}/blockers", s.handleBlock)
+	s.mux.HandleFunc("DELETE /tasks/{id}/blockers/{blocker}", s.handleUnblock)
+	s.mux.HandleFunc("POST /tasks/{id}/start", s.handleStart)
+	s.mux.HandleFunc("POST /timer/stop", s.handleStop)
+	s.mux.HandleFunc("GET /next", s.handleNext)
+	s.mux.HandleFunc("GET /search", s.handleSearch)
+	s.mux.HandleFunc("GET /completed", s.handleCompleted)
+	s.mux.HandleFunc("GET /export", s.handleExport)
+	s.mux.HandleFunc("GET /archive", s.handleArchived)
+	s.mux.HandleFunc("POST /archive", s.handleArchive)
+	s.mux.HandleFunc("POST /archive/purge", s.handlePurge)
+	s.mux.HandleFunc("POST /import", s.handleImport)
+	s.mux.HandleFunc("POST /undo", s.handleUndo)
+	s.mux.HandleFunc("POST /redo", s.handleRedo)
+	s.mux.HandleFunc("GET /journal", s.handleJournal)
+	s.mux.HandleFunc("POST /journal/trim", s.handleTrimJournal)
+	s.mux.HandleFunc("GET /projects", s.handleProjects)
+	s.mux.HandleFunc("GET /projects/default", s.handleDefaultProject)
+	s.mux.HandleFunc("PUT /projects/default", s.handleSetDefaultProject)
+	s.mux.HandleFunc("GET /sync/dir", s.handleSyncDir)
+	s.mux.HandleFunc("PUT /sync/dir", s.handleSetSyncDir)
+	s.mux.HandleFunc("POST /sync", s.handleSync)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !isLoopbackHost(r.Host) {
+		writeError(w, http.StatusForbidden, codeForbidden, "host "+r.Host+" is not a loopback address")
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !isLoopbackOrigin(origin) {
+		writeError(w, http.StatusForbidden, codeForbidden, "cross-site requests are not allowed")
+		return
+	}
+	if s.token != "" && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// NewToken returns a random bearer token.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// isLoopbackHost reports whether host, with or without a port, is localhost or
+// a loopback IP address.
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isLoopbackOrigin reports whether a browser Origin header names a page
+// served from this machine.
+func isLoopbackOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && isLoopbackHost(u.Host)
+}
+
+// decodeBody decodes a JSON request body into v, answering 415 unless it was
+// sent as application/json and 400 when it is malformed.
+func decodeBody(w http.ResponseWriter, r *http.Request, what string, v any) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, codeUnsupportedType, "request body must be application/json")
+		return false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid "+what+": "+err.Error())
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	all, err := parseBool(query.Get("all"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid all parameter: "+query.Get("all"))
+		return
+	}
+
+	var tasks []storage.Task
+	if all {
+		tasks, err = s.store.GetIncompleteTasks()
+	} else {
+		project := query.Get("project")
+		if project == "" {
+			project, err = s.store.DefaultProject()
+		} else {
+			project, err = storage.NormalizeProject(project)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+				return
+			}
+		}
+		if err == nil {
+			tasks, err = s.store.GetProjectTasks(project)
+		}
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+func (s *Server) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var template storage.Task
+	if !decodeBody(w, r, "task", &template) {
+		return
+	}
+
+	if strings.TrimSpace(template.Description) == "" {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "task description cannot be empty")
+		return
+	}
+	if template.Project != "" {
+		project, err := storage.NormalizeProject(template.Project)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+			return
+		}
+		template.Project = project
+	}
+
+	task, err := s.store.AddTask(template)
+	if errors.Is(err, storage.ErrTaskNotFound) {
+		// A missing parent or blocker is a problem with the request body,
+		// not with the URL.
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidReference, err.Error())
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/tasks/%d", task.ID))
+	writeJSON(w, http.StatusCreated, task)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	task, err := s.store.ResolveTask(r.PathValue("ref"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	force, err := parseBool(r.URL.Query().Get("force"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid force parameter: "+r.URL.Query().Get("force"))
+		return
+	}
+
+	task, err := s.store.CompleteTask(id, force)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	task, err := s.store.DeleteTask(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleCompleteBatch(w http.ResponseWriter, r *http.Request) {
+	force, err := parseBool(r.URL.Query().Get("force"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid force parameter: "+r.URL.Query().Get("force"))
+		return
+	}
+
+	sel, ok := decodeSelection(w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := s.store.CompleteTasks(sel, force)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+func (s *Server) handleDeleteBatch(w http.ResponseWriter, r *http.Request) {
+	sel, ok := decodeSelection(w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := s.store.DeleteTasks(sel)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+// decodeSelection reads a selection from the request body, answering 400
+// when it is malformed.
+func decodeSelection(w http.ResponseWriter, r *http.Request) (storage.Selection, bool) {
+	var sel storage.Selection
+	if !decodeBody(w, r, "selection", &sel) {
+		return sel, false
+	}
+
+	if sel.Project != "" {
+		project, err := storage.NormalizeProject(sel.Project)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+			return sel, false
+		}
+		sel.Project = project
+	}
+	return sel, true
+}
+
+func (s *Server) handleCompleted(w http.ResponseWriter, r *http.Request) {
+	var bounds [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("invalid %s parameter: %s (use RFC 3339)", name, value))
+			return
+		}
+		bounds[i] = t
+	}
+
+	tasks, err := s.store.GetCompletedTasks(bounds[0], bounds[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+func (s *Server) handleDefaultProject(w http.ResponseWriter, r *http.Request) {
+	project, err := s.store.DefaultProject()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, projectResponse{Project: project})
+}
+
+func (s *Server) handleEdit(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var edit storage.TaskEdit
+	if !decodeBody(w, r, "edit", &edit) {
+		return
+	}
+
+	task, err := s.store.EditTask(id, edit)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var body projectResponse
+	if !decodeBody(w, r, "project", &body) {
+		return
+	}
+
+	task, err := s.store.MoveTask(id, body.Project)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleBlock(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var body blockerRequest
+	if !decodeBody(w, r, "blocker", &body) {
+		return
+	}
+
+	task, err := s.store.AddDependency(id, body.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleUnblock(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	blocker, err := strconv.Atoi(r.PathValue("blocker"))
+	if err != nil || blocker < 1 {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid task ID: "+r.PathValue("blocker"))
+		return
+	}
+
+	task, err := s.store.RemoveDependency(id, blocker)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	task, stopped, err := s.store.StartTask(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, startResponse{Task: task, Stopped: stopped})
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	task, err := s.store.StopTask()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleNext(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, ok := queryInt(w, r, "limit")
+	if !ok {
+		return
+	}
+
+	project := query.Get("project")
+	var err error
+	if project == "" {
+		project, err = s.store.DefaultProject()
+	} else {
+		project, err = storage.NormalizeProject(project)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+			return
+		}
+	}
+
+	var tasks []storage.Task
+	if err == nil {
+		tasks, err = s.store.NextTasks(project, limit)
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	regex, err := parseBool(query.Get("regex"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid regex parameter: "+query.Get("regex"))
+		return
+	}
+
+	tasks, err := s.store.SearchTasks(query.Get("q"), regex)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	tasks, err := s.store.GetAllTasks()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+func (s *Server) handleArchived(w http.ResponseWriter, r *http.Request) {
+	tasks, err := s.store.GetArchivedTasks()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	before, dryRun, ok := archiveParams(w, r)
+	if !ok {
+		return
+	}
+	if before.IsZero() {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "missing before parameter")
+		return
+	}
+
+	tasks, err := s.store.ArchiveTasks(before, dryRun)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
+	before, dryRun, ok := archiveParams(w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := s.store.PurgeArchive(before, dryRun)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tasks))
+}
+
+// archiveParams parses the ?before= and ?dry_run= parameters of the archive
+// routes, answering 400 when either is malformed.
+func archiveParams(w http.ResponseWriter, r *http.Request) (before time.Time, dryRun bool, ok bool) {
+	query := r.URL.Query()
+	if value := query.Get("before"); value != "" {
+		var err error
+		if before, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid before parameter: "+value+" (use RFC 3339)")
+			return before, false, false
+		}
+	}
+	dryRun, err := parseBool(query.Get("dry_run"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid dry_run parameter: "+query.Get("dry_run"))
+		return before, false, false
+	}
+	return before, dryRun, true
+}
+
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := parseBool(r.URL.Query().Get("dry_run"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid dry_run parameter: "+r.URL.Query().Get("dry_run"))
+		return
+	}
+	var tasks []storage.Task
+	if !decodeBody(w, r, "tasks", &tasks) {
+		return
+	}
+
+	result, err := s.store.ImportTasks(tasks, dryRun)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleUndo(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.store.Undo()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s *Server) handleRedo(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.store.Redo()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s *Server) handleJournal(w http.ResponseWriter, r *http.Request) {
+	limit, ok := queryInt(w, r, "limit")
+	if !ok {
+		return
+	}
+
+	entries, err := s.store.GetJournal(limit)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []storage.JournalEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) handleTrimJournal(w http.ResponseWriter, r *http.Request) {
+	keep, ok := queryInt(w, r, "keep")
+	if !ok {
+		return
+	}
+
+	removed, err := s.store.TrimJournal(keep)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, trimResponse{Removed: removed})
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := s.store.GetProjects()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if projects == nil {
+		projects = []storage.ProjectInfo{}
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (s *Server) handleSetDefaultProject(w http.ResponseWriter, r *http.Request) {
+	var body projectResponse
+	if !decodeBody(w, r, "project", &body) {
+		return
+	}
+	if err := s.store.SetDefaultProject(body.Project); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	s.handleDefaultProject(w, r)
+}
+
+func (s *Server) handleSyncDir(w http.ResponseWriter, r *http.Request) {
+	dir, err := s.store.SyncDir()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, syncDirBody{Dir: dir})
+}
+
+func (s *Server) handleSetSyncDir(w http.ResponseWriter, r *http.Request) {
+	var body syncDirBody
+	if !decodeBody(w, r, "sync directory", &body) {
+		return
+	}
+	if !filepath.IsAbs(body.Dir) {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "sync directory must be an absolute path")
+		return
+	}
+	if err := s.store.SetSyncDir(body.Dir); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	var body syncDirBody
+	if !decodeBody(w, r, "sync directory", &body) {
+		return
+	}
+	if !filepath.IsAbs(body.Dir) {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "sync directory must be an absolute path")
+		return
+	}
+
+	result, err := s.store.Sync(body.Dir)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// pathID parses the {id} path segment, answering 400 when it is not a
+// positive integer.
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid task ID: "+r.PathValue("id"))
+		return 0, false
+	}
+	return id, true
+}
+
+// queryInt parses an optional non-negative integer parameter, which defaults
+// to 0, answering 400 when it is malformed.
+func queryInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		writeError(w, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("invalid %s parameter: %s", name, value))
+		return 0, false
+	}
+	return n, true
+}
+
+func parseBool(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// nonNil makes empty results encode as [] rather than null.
+func nonNil(tasks []storage.Task) []storage.Task {
+	if tasks == nil {
+		return []storage.Task{}
+	}
+	return tasks
+}
+
+// writeStoreError maps a store error to a status code.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrTaskNotFound):
+		writeError(w, http.StatusNotFound, codeNotFound, err.Error())
+	case errors.Is(err, storage.ErrAmbiguousTask):
+		writeError(w, http.StatusConflict, codeAmbiguous, err.Error())
+	case errors.Is(err, storage.ErrAlreadyCompleted):
+		writeError(w, http.StatusConflict, codeAlreadyCompleted, err.Error())
+	case errors.Is(err, storage.ErrOpenSubtasks):
+		writeError(w, http.StatusConflict, codeOpenSubtasks, err.Error())
+	case errors.Is(err, storage.ErrInvalidSelection):
+		writeError(w, http.StatusBadRequest, codeInvalidSelection, err.Error())
+	case errors.Is(err, storage.ErrDependencyLoop):
+		writeError(w, http.StatusConflict, codeDependencyLoop, err.Error())
+	case errors.Is(err, storage.ErrAlreadyRunning):
+		writeError(w, http.StatusConflict, codeAlreadyRunning, err.Error())
+	case errors.Is(err, storage.ErrNoRunningTask):
+		writeError(w, http.StatusConflict, codeNoRunningTask, err.Error())
+	case errors.Is(err, storage.ErrNothingToUndo):
+		writeError(w, http.StatusConflict, codeNothingToUndo, err.Error())
+	case errors.Is(err, storage.ErrNothingToRedo):
+		writeError(w, http.StatusConflict, codeNothingToRedo, err.Error())
+	case errors.Is(err, storage.ErrTaskChanged):
+		writeError(w, http.StatusConflict, codeTaskChanged, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: message, Code: code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
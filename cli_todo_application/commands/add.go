@@ -4,33 +4,117 @@ import (
 	"cli_todo_application/storage"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	addDue      string
+	addPriority string
+	addTags     []string
+	addRepeat   string
+	addParent   string
+	addBlockers []string
+)
+
 var addCmd = &cobra.Command{
 	Use:   "add [task description]",
 	Short: "Add a new task to your TODO list",
-	Long:  `Add a new task to your TODO list with the provided description.`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Add a new task to your TODO list with the provided description.
+
+Optionally attach a due date, a priority and any number of tags:
+
+  task add --due 2026-10-20 --priority high --tag backend Fix login bug
+
+Recurring tasks take a --repeat rule: daily, weekdays, weekly:mon,thu or
+monthly:15. Completing one instance creates the next with the following
+due date. Without --due, the first instance is due on the first day the
+rule falls on, starting today.
+
+Use --parent to add a subtask below an existing task and --blocked-by to
+record tasks that must be finished first. Both take task IDs or unique
+description prefixes.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		description := strings.Join(args, " ")
 		if strings.TrimSpace(description) == "" {
 			return fmt.Errorf("task description cannot be empty")
 		}
 
-		store, err := storage.NewTaskStore()
+		template := storage.Task{Description: description, Tags: addTags}
+
+		if addDue != "" {
+			due, err := parseDate(addDue)
+			if err != nil {
+				return err
+			}
+			template.Due = &due
+		}
+
+		if addRepeat != "" {
+			start := time.Now()
+			if template.Due != nil {
+				start = *template.Due
+			}
+			recurrence, err := storage.ParseRecurrence(addRepeat, start)
+			if err != nil {
+				return err
+			}
+			template.Recurrence = recurrence
+
+			if template.Due == nil {
+				due := recurrence.First(start)
+				template.Due = &due
+			}
+		}
+
+		priority, err := storage.ParsePriority(addPriority)
+		if err != nil {
+			return err
+		}
+		template.Priority = priority
+
+		store, err := openService()
 		if err != nil {
 			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
 		defer store.Close()
 
-		task, err := store.AddTask(description)
+		if template.Project, err = selectedProject(store); err != nil {
+			return err
+		}
+
+		if addParent != "" {
+			parent, err := store.ResolveTask(addParent)
+			if err != nil {
+				return fmt.Errorf("invalid parent: %w", err)
+			}
+			template.ParentID = parent.ID
+		}
+		for _, ref := range addBlockers {
+			blocker, err := store.ResolveTask(ref)
+			if err != nil {
+				return fmt.Errorf("invalid blocker: %w", err)
+			}
+			template.BlockedBy = append(template.BlockedBy, blocker.ID)
+		}
+
+		task, err := store.AddTask(template)
 		if err != nil {
 			return fmt.Errorf("failed to add task: %w", err)
 		}
 
-		fmt.Printf("Added \"%s\" to your task list.\n", task.Description)
+		fmt.Printf("Added \"%s\" to your %s task list.\n", task.Description, task.ProjectName())
 		return nil
 	},
-}
\ No newline at end of file
+}
+
+func init() {
+	addCmd.Flags().StringVar(&addDue, "due", "", "due date (YYYY-MM-DD, today, tomorrow or +Nd)")
+	addCmd.Flags().StringVarP(&addPriority, "priority", "p", "", "priority: low, medium or high")
+	addCmd.Flags().StringSliceVarP(&addTags, "tag", "t", nil, "tag to attach (repeatable)")
+	addCmd.Flags().StringVar(&addParent, "parent", "", "add as a subtask of this task")
+	addCmd.Flags().StringSliceVarP(&addBlockers, "blocked-by", "b", nil, "task that must be completed first (repeatable)")
+	addCmd.Flags().StringVarP(&addRepeat, "repeat", "r", "", "recurrence: daily, weekdays, weekly:mon,thu or monthly:15")
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"cli_todo_application/remind"
+	"cli_todo_application/storage"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var agendaDays int
+
+var agendaCmd = &cobra.Command{
+	Use:     "agenda",
+	Aliases: []string{"remind"},
+	Short:   "Show overdue tasks and tasks coming due",
+	Long: `Show the incomplete tasks that are overdue, due today or due within the
+next few days (3 unless --days is given). Tasks from every project are
+shown unless --project is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if agendaDays < 0 {
+			return fmt.Errorf("invalid number of days: %d", agendaDays)
+		}
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		tasks, err := agendaTasks(store)
+		if err != nil {
+			return err
+		}
+
+		agenda := remind.BuildAgenda(tasks, time.Now(), agendaDays)
+		if agenda.Empty() {
+			fmt.Printf("Nothing is overdue or due in the next %d days.\n", agendaDays)
+			return nil
+		}
+
+		printAgendaGroup("Overdue:", agenda.Overdue)
+		printAgendaGroup("Due today:", agenda.DueToday)
+		printAgendaGroup(fmt.Sprintf("Due in the next %d days:", agendaDays), agenda.DueSoon)
+		return nil
+	},
+}
+
+// agendaTasks returns the incomplete tasks in the project given with
+// --project, or in every project.
+func agendaTasks(store taskService) ([]storage.Task, error) {
+	if projectFlag == "" {
+		tasks, err := store.GetIncompleteTasks()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tasks: %w", err)
+		}
+		return tasks, nil
+	}
+
+	project, err := storage.NormalizeProject(projectFlag)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := store.GetProjectTasks(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+func printAgendaGroup(heading string, tasks []storage.Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	fmt.Println(heading)
+	for _, task := range tasks {
+		fmt.Printf("%d. %s%s (in %s)\n", task.ID, task.Description, formatTaskDetails(task), task.ProjectName())
+	}
+}
+
+func init() {
+	agendaCmd.Flags().IntVarP(&agendaDays, "days", "d", 3, "how many days after today count as due soon")
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"bufio"
+	"cli_todo_application/storage"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveOlderThan string
+	archiveYes       bool
+	purgeOlderThan   string
+	purgeYes         bool
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Move old completed tasks out of the active task list",
+	Long: `Move tasks completed more than --older-than ago (30d by default) to the
+archive. Archived tasks no longer slow down everyday commands but still
+show up in "task completed", "task stats", "task report" and "task search".
+Archiving can be undone with "task undo".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		age, err := parseAge(archiveOlderThan)
+		if err != nil {
+			return err
+		}
+		cutoff := time.Now().Add(-age)
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		tasks, err := store.ArchiveTasks(cutoff, true)
+		if err != nil {
+			return fmt.Errorf("failed to find tasks to archive: %w", err)
+		}
+		if len(tasks) == 0 {
+			fmt.Printf("No tasks were completed more than %s ago.\n", archiveOlderThan)
+			return nil
+		}
+
+		if !archiveYes && !confirm(fmt.Sprintf("Archive %d completed tasks?", len(tasks))) {
+			fmt.Println("Nothing was archived.")
+			return nil
+		}
+
+		tasks, err = store.ArchiveTasks(cutoff, false)
+		if err != nil {
+			return fmt.Errorf("failed to archive tasks: %w", err)
+		}
+		fmt.Printf("Archived %d completed tasks.\n", len(tasks))
+		return nil
+	},
+}
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently delete archived tasks",
+	Long: `Permanently delete every archived task, or only those completed more than
+--older-than ago. Purged tasks are gone from history and statistics, and
+the undo history that refers to them is discarded, so this cannot be
+undone. Purging does not delete the tasks on machines you sync with.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var cutoff time.Time
+		if purgeOlderThan != "" {
+			age, err := parseAge(purgeOlderThan)
+			if err != nil {
+				return err
+			}
+			cutoff = time.Now().Add(-age)
+		}
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		tasks, err := store.PurgeArchive(cutoff, true)
+		if err != nil {
+			return fmt.Errorf("failed to find tasks to purge: %w", err)
+		}
+		if len(tasks) == 0 {
+			fmt.Println("There are no archived tasks to purge.")
+			return nil
+		}
+
+		if !purgeYes && !confirm(fmt.Sprintf("Permanently delete %d archived tasks? This cannot be undone.", len(tasks))) {
+			fmt.Println("Nothing was purged.")
+			return nil
+		}
+
+		tasks, err = store.PurgeArchive(cutoff, false)
+		if err != nil {
+			return fmt.Errorf("failed to purge tasks: %w", err)
+		}
+		fmt.Printf("Purged %d archived tasks.\n", len(tasks))
+		return nil
+	},
+}
+
+// confirm asks a yes/no question on the terminal and reports whether the
+// answer was yes. Anything else, including end of input, means no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// archivedLabel marks archived tasks in listings.
+func archivedLabel(task storage.Task) string {
+	if task.Archived {
+		return " (archived)"
+	}
+	return ""
+}
+
+func init() {
+	archiveCmd.Flags().StringVar(&archiveOlderThan, "older-than", "30d", "archive tasks completed longer ago than this (e.g. 30d, 2w)")
+	archiveCmd.Flags().BoolVarP(&archiveYes, "yes", "y", false, "do not ask for confirmation")
+	purgeCmd.Flags().StringVar(&purgeOlderThan, "older-than", "", "only purge tasks completed longer ago than this")
+	purgeCmd.Flags().BoolVarP(&purgeYes, "yes", "y", false, "do not ask for confirmation")
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var blockCmd = &cobra.Command{
+	Use:   "block [task] [blocking task]",
+	Short: "Record that a task is waiting on another task",
+	Long: `Mark the first task as blocked by the second. Blocked tasks are not
+suggested by "task next" until every task blocking them is completed.
+Both tasks can be given as IDs or unique description prefixes.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		task, err := store.ResolveTask(args[0])
+		if err != nil {
+			return err
+		}
+		blocker, err := store.ResolveTask(args[1])
+		if err != nil {
+			return err
+		}
+
+		if _, err := store.AddDependency(task.ID, blocker.ID); err != nil {
+			return fmt.Errorf("failed to add dependency: %w", err)
+		}
+
+		fmt.Printf("\"%s\" is now blocked by \"%s\".\n", task.Description, blocker.Description)
+		return nil
+	},
+}
+
+var unblockCmd = &cobra.Command{
+	Use:   "unblock [task] [blocking task]",
+	Short: "Remove a dependency between two tasks",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		task, err := store.ResolveTask(args[0])
+		if err != nil {
+			return err
+		}
+		blocker, err := store.ResolveTask(args[1])
+		if err != nil {
+			return err
+		}
+
+		if _, err := store.RemoveDependency(task.ID, blocker.ID); err != nil {
+			return fmt.Errorf("failed to remove dependency: %w", err)
+		}
+
+		fmt.Printf("\"%s\" is no longer blocked by \"%s\".\n", task.Description, blocker.Description)
+		return nil
+	},
+}
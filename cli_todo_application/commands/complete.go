@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"cli_todo_application/tui"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// completeTaskRefs returns a completion function offering the IDs of open
+// tasks, or their positions in the selected project when *byPosition is set,
+// with each task's description as the hint. Only the first maxArgs arguments
+// are completed.
+func completeTaskRefs(byPosition *bool, maxArgs int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= maxArgs {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		store, err := openService()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		defer store.Close()
+
+		var tasks []storage.Task
+		if *byPosition {
+			project, err := selectedProject(store)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			tasks, err = store.GetProjectTasks(project)
+		} else {
+			tasks, err = store.GetIncompleteTasks()
+		}
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var completions []string
+		for i, task := range tasks {
+			ref := strconv.Itoa(task.ID)
+			if *byPosition {
+				ref = strconv.Itoa(i + 1)
+			}
+			if strings.HasPrefix(ref, toComplete) && !slices.Contains(args, ref) {
+				completions = append(completions, ref+"\t"+task.Description)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// pickTask lets the user choose one of the selected project's open tasks with
+// a fuzzy finder. It needs an interactive terminal.
+func pickTask(store taskService) (*storage.Task, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, fmt.Errorf("no task given (pass a task ID or description prefix)")
+	}
+
+	project, err := selectedProject(store)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := store.GetProjectTasks(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("there are no incomplete tasks in %s", project)
+	}
+
+	items := make([]string, len(tasks))
+	for i, task := range tasks {
+		items[i] = fmt.Sprintf("%d. %s%s", task.ID, task.Description, formatTaskDetails(task))
+	}
+
+	choice, err := tui.Pick("Task", items)
+	if errors.Is(err, tui.ErrCancelled) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tasks[choice], nil
+}
@@ -1,42 +1,64 @@
 package commands
 
 import (
-	"cli_todo_application/storage"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+var completedRange dateRange
+
 var completedCmd = &cobra.Command{
 	Use:   "completed",
-	Short: "List all tasks completed today",
-	Long:  `Display all tasks that were completed today.`,
+	Short: "List tasks completed today or in a date range",
+	Long: `Display all tasks that were completed today. Use --since and --until, or
+--week or --month, to look further back. Days are evaluated in the local
+timezone.`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		store, err := storage.NewTaskStore()
+		from, to, err := completedRange.resolve(1)
+		if err != nil {
+			return err
+		}
+
+		store, err := openService()
 		if err != nil {
 			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
 		defer store.Close()
 
-		tasks, err := store.GetCompletedTasksToday()
+		tasks, err := store.GetCompletedTasks(from, to)
 		if err != nil {
 			return fmt.Errorf("failed to get completed tasks: %w", err)
 		}
 
+		period := describeRange(from, to)
 		if len(tasks) == 0 {
-			fmt.Println("You have not completed any tasks today.")
+			fmt.Printf("You have not completed any tasks %s.\n", period)
 			return nil
 		}
 
-		fmt.Println("You have finished the following tasks today:")
+		// Only a single day is shown with bare times.
+		timeLayout := "15:04"
+		if from.IsZero() || to.Sub(from) > 25*time.Hour {
+			timeLayout = dateLayout + " 15:04"
+		}
+
+		fmt.Printf("You have finished the following tasks %s:\n", period)
 		for _, task := range tasks {
-			completedTime := "unknown time"
-			if task.CompletedAt != nil {
-				completedTime = task.CompletedAt.Format("15:04")
+			completedTime := task.CompletedAt.Local().Format(timeLayout)
+			repeats := ""
+			if task.Recurrence != nil {
+				repeats = ", repeats " + task.Recurrence.String()
 			}
-			fmt.Printf("- %s (completed at %s)\n", task.Description, completedTime)
+			fmt.Printf("- %s (completed at %s%s)\n", task.Description, completedTime, repeats)
 		}
 
 		return nil
 	},
-}
\ No newline at end of file
+}
+
+func init() {
+	completedRange.register(completedCmd)
+}
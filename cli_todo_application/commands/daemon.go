@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"cli_todo_application/remind"
+	"cli_todo_application/storage"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonInterval time.Duration
+	daemonNotify   string
+	daemonDays     int
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Send notifications about overdue and due-soon tasks",
+	Long: `Check the tasks at a fixed interval and send a notification for every
+task that is overdue, due today or due within --days days. Each task is
+announced at most once a day for each of these states.
+
+Notifications go to one of:
+
+  stdout                print a line to standard output (the default)
+  command:CMD [ARGS]    run CMD ARGS TITLE BODY, e.g. command:notify-send
+  webhook:URL           POST the notification as JSON to a local URL
+
+The database is only opened for the duration of each check, and checks go
+through "task serve" when it is running. Stop the daemon with Ctrl-C.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		notifier, err := remind.ParseNotifier(daemonNotify, os.Stdout)
+		if err != nil {
+			return err
+		}
+		if daemonDays < 0 {
+			return fmt.Errorf("invalid number of days: %d", daemonDays)
+		}
+
+		daemon := &remind.Daemon{
+			Open:     agendaSource,
+			Notifier: notifier,
+			Clock:    remind.SystemClock,
+			Interval: daemonInterval,
+			Days:     daemonDays,
+			OnError: func(err error) {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", time.Now().Format("2006-01-02 15:04:05"), err)
+			},
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return daemon.Run(ctx)
+	},
+}
+
+// projectSource restricts a task service to the project given with --project.
+type projectSource struct {
+	taskService
+}
+
+func (s projectSource) GetIncompleteTasks() ([]storage.Task, error) {
+	return agendaTasks(s.taskService)
+}
+
+func agendaSource() (remind.TaskSource, error) {
+	store, err := openService()
+	if err != nil {
+		return nil, err
+	}
+	return projectSource{store}, nil
+}
+
+func init() {
+	daemonCmd.Flags().DurationVarP(&daemonInterval, "interval", "i", 5*time.Minute, "time between checks")
+	daemonCmd.Flags().StringVarP(&daemonNotify, "notify", "N", "stdout", "notifier: stdout, command:CMD or webhook:URL")
+	daemonCmd.Flags().IntVarP(&daemonDays, "days", "d", 1, "how many days after today count as due soon")
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseDate accepts an ISO date (2006-01-02), "today", "tomorrow" or a relative
+// offset in days such as "+3d", and returns midnight of that day in local time.
+func parseDate(value string) (time.Time, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	today := storage.StartOfDay(time.Now())
+
+	switch value {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if strings.HasPrefix(value, "+") && strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(value, "+"), "d"))
+		if err == nil {
+			return today.AddDate(0, 0, days), nil
+		}
+	}
+
+	date, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %q (use YYYY-MM-DD, today, tomorrow or +Nd)", value)
+	}
+	return date, nil
+}
+
+// parseAge accepts an age in days or weeks ("30d", "2w") or a Go duration
+// such as "12h".
+func parseAge(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) {
+			if n < 0 {
+				break
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age: %q (use 30d, 2w or 12h)", value)
+	}
+	return age, nil
+}
+
+// dateRange holds the --since, --until, --week and --month flags shared by
+// commands that report on completed tasks. All ranges use local days.
+type dateRange struct {
+	since string
+	until string
+	week  bool
+	month bool
+}
+
+func (r *dateRange) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&r.since, "since", "", "start date, inclusive (YYYY-MM-DD, today, yesterday)")
+	cmd.Flags().StringVar(&r.until, "until", "", "end date, inclusive (YYYY-MM-DD, today, yesterday)")
+	cmd.Flags().BoolVar(&r.week, "week", false, "the current week, starting Monday")
+	cmd.Flags().BoolVar(&r.month, "month", false, "the current calendar month")
+	cmd.MarkFlagsMutuallyExclusive("week", "month", "since")
+	cmd.MarkFlagsMutuallyExclusive("week", "month", "until")
+}
+
+// isSet reports whether any range flag was given.
+func (r *dateRange) isSet() bool {
+	return r.since != "" || r.until != "" || r.week || r.month
+}
+
+// resolve returns the half-open interval [from, to) selected by the flags. When
+// none are given, the range covers the last defaultDays days including today.
+func (r *dateRange) resolve(defaultDays int) (time.Time, time.Time, error) {
+	today := storage.StartOfDay(time.Now())
+	tomorrow := today.AddDate(0, 0, 1)
+
+	switch {
+	case r.week:
+		offset := (int(today.Weekday()) + 6) % 7
+		monday := today.AddDate(0, 0, -offset)
+		return monday, monday.AddDate(0, 0, 7), nil
+	case r.month:
+		first := today.AddDate(0, 0, 1-today.Day())
+		return first, first.AddDate(0, 1, 0), nil
+	case !r.isSet():
+		return today.AddDate(0, 0, 1-defaultDays), tomorrow, nil
+	}
+
+	var from time.Time
+	to := tomorrow
+
+	if r.since != "" {
+		since, err := parseDate(r.since)
+		if err != nil {
+			return from, to, err
+		}
+		from = since
+	}
+	if r.until != "" {
+		until, err := parseDate(r.until)
+		if err != nil {
+			return from, to, err
+		}
+		to = until.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !from.Before(to) {
+		return from, to, fmt.Errorf("--since must not be after --until")
+	}
+	return from, to, nil
+}
+
+// describeRange renders the interval for report headings, e.g. "today" or
+// "between 2026-10-12 and 2026-10-18".
+func describeRange(from, to time.Time) string {
+	last := to.AddDate(0, 0, -1)
+	today := storage.StartOfDay(time.Now())
+
+	switch {
+	case from.IsZero():
+		return "up to " + last.Format(dateLayout)
+	case from.Equal(last) && from.Equal(today):
+		return "today"
+	case from.Equal(last):
+		return "on " + from.Format(dateLayout)
+	}
+	return fmt.Sprintf("between %s and %s", from.Format(dateLayout), last.Format(dateLayout))
+}
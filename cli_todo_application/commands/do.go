@@ -2,39 +2,94 @@ package commands
 
 import (
 	"cli_todo_application/storage"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	doByPosition bool
+	doForce      bool
+	doFilter     taskFilter
+)
+
 var doCmd = &cobra.Command{
-	Use:   "do [task number]",
-	Short: "Mark a task on your TODO list as complete",
-	Long:  `Mark a task as complete by providing its number from the list.`,
-	Args:  cobra.ExactArgs(1),
+	Use:   "do [task id | range | description prefix]...",
+	Short: "Mark tasks on your TODO list as complete",
+	Long: `Mark tasks as complete by providing their IDs from the list, ranges of
+IDs such as 5-7, or unique prefixes of their descriptions. Use --position
+to pass positions (and ranges of positions) in the incomplete list instead
+of IDs. The filter flags complete every matching task in the selected
+project, or narrow down the tasks given as arguments.
+
+All tasks are completed together or not at all, and positions refer to the
+list as it was before the command ran.
+
+Without arguments or filters, pick the task from the selected project with
+an interactive fuzzy finder.
+
+A task with open subtasks can only be completed with --force, which
+completes the subtasks as well, unless the subtasks are completed in the
+same command.`,
+	ValidArgsFunction: completeTaskRefs(&doByPosition, math.MaxInt),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		taskNum, err := strconv.Atoi(args[0])
+		store, err := openService()
 		if err != nil {
-			return fmt.Errorf("invalid task number: %s", args[0])
+			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
+		defer store.Close()
 
-		if taskNum < 1 {
-			return fmt.Errorf("task number must be greater than 0")
+		byPosition := doByPosition
+		if len(args) == 0 && !doFilter.isSet() {
+			target, err := pickTask(store)
+			if err != nil {
+				return err
+			}
+			if target == nil {
+				fmt.Println("No task was completed.")
+				return nil
+			}
+			args, byPosition = []string{strconv.Itoa(target.ID)}, false
 		}
 
-		store, err := storage.NewTaskStore()
+		sel, err := taskSelection(store, args, byPosition, &doFilter)
 		if err != nil {
-			return fmt.Errorf("failed to initialize storage: %w", err)
+			return err
 		}
-		defer store.Close()
 
-		task, err := store.CompleteTask(taskNum)
+		tasks, err := store.CompleteTasks(sel, doForce)
+		if errors.Is(err, storage.ErrOpenSubtasks) {
+			return fmt.Errorf("failed to complete tasks: %w (use --force to complete them too)", err)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to complete task: %w", err)
+			return fmt.Errorf("failed to complete tasks: %w", err)
+		}
+
+		if len(tasks) == 0 {
+			fmt.Println("No tasks match the given filters.")
+			return nil
 		}
 
-		fmt.Printf("You have completed the \"%s\" task.\n", task.Description)
+		for _, task := range tasks {
+			fmt.Printf("You have completed the \"%s\" task.\n", task.Description)
+
+			if task.NextID != 0 {
+				next, err := store.GetTask(task.NextID)
+				if err != nil {
+					return fmt.Errorf("failed to get next occurrence: %w", err)
+				}
+				fmt.Printf("The next occurrence (%d) is due %s.\n", next.ID, next.Due.Format(dateLayout))
+			}
+		}
 		return nil
 	},
-}
\ No newline at end of file
+}
+
+func init() {
+	doCmd.Flags().BoolVarP(&doByPosition, "position", "n", false, "treat the arguments as positions in the incomplete list")
+	doCmd.Flags().BoolVarP(&doForce, "force", "f", false, "complete tasks even if they have open subtasks")
+	doFilter.register(doCmd, "complete")
+}
@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	editByPosition bool
+	editDue        string
+	editNoDue      bool
+	editPriority   string
+	editTags       []string
+	editUntags     []string
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit [task id | description prefix] [new description]",
+	Short: "Change a task's description or details",
+	Long: `Update a task. A new description can be given after the task reference;
+without one, and without any of the flags below, the description is
+opened in $VISUAL or $EDITOR. Lines starting with # are ignored.
+
+Due date, priority and tags can be changed with flags:
+
+  task edit 4 --due tomorrow --priority high --tag urgent --untag someday`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeTaskRefs(&editByPosition, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		description := strings.TrimSpace(strings.Join(args[1:], " "))
+		flagsSet := cmd.Flags().Changed("due") || editNoDue || cmd.Flags().Changed("priority") ||
+			len(editTags) > 0 || len(editUntags) > 0
+
+		var due *time.Time
+		if editDue != "" {
+			parsed, err := parseDate(editDue)
+			if err != nil {
+				return err
+			}
+			due = &parsed
+		}
+
+		edit := storage.TaskEdit{
+			Due:        due,
+			ClearDue:   editNoDue,
+			AddTags:    editTags,
+			RemoveTags: editUntags,
+		}
+		if cmd.Flags().Changed("priority") {
+			priority, err := storage.ParsePriority(editPriority)
+			if err != nil {
+				return err
+			}
+			edit.Priority = &priority
+		}
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer func() { store.Close() }()
+
+		target, err := resolveTaskRef(store, args[0], editByPosition)
+		if err != nil {
+			return err
+		}
+		edit.UnchangedSince = &target.UpdatedAt
+
+		if description == "" && !flagsSet {
+			// The database stays locked while it is open, so close it for
+			// as long as the editor runs.
+			store.Close()
+			if description, err = editInEditor(target.Description); err != nil {
+				return err
+			}
+			if description == target.Description {
+				fmt.Println("Description unchanged.")
+				return nil
+			}
+			reopened, err := openService()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			store = reopened
+		}
+		edit.Description = description
+
+		task, err := store.EditTask(target.ID, edit)
+		if errors.Is(err, storage.ErrTaskChanged) {
+			return fmt.Errorf("task %d was changed while it was being edited; run \"task edit\" again", target.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to edit task: %w", err)
+		}
+
+		fmt.Printf("Updated task %d: %s%s\n", task.ID, task.Description, formatTaskDetails(*task))
+		return nil
+	},
+}
+
+// editInEditor opens text in the user's editor and returns the edited text
+// with comment lines removed and surrounding whitespace trimmed.
+func editInEditor(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	file, err := os.CreateTemp("", "task-edit-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	content := text + "\n# Edit the task description above. Lines starting with # are ignored.\n"
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	// The editor setting may carry arguments, e.g. "code --wait".
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read temporary file: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(edited), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	description := strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+	if description == "" {
+		return "", fmt.Errorf("task description cannot be empty")
+	}
+	return description, nil
+}
+
+func init() {
+	editCmd.Flags().BoolVarP(&editByPosition, "position", "n", false, "treat the argument as a position in the selected project")
+	editCmd.Flags().StringVar(&editDue, "due", "", "new due date (YYYY-MM-DD, today, tomorrow or +Nd)")
+	editCmd.Flags().BoolVar(&editNoDue, "no-due", false, "remove the due date")
+	editCmd.Flags().StringVarP(&editPriority, "priority", "p", "", "new priority: none, low, medium or high")
+	editCmd.Flags().StringSliceVarP(&editTags, "tag", "t", nil, "tag to add (repeatable)")
+	editCmd.Flags().StringSliceVar(&editUntags, "untag", nil, "tag to remove (repeatable)")
+	editCmd.MarkFlagsMutuallyExclusive("due", "no-due")
+}
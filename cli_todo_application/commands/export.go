@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"cli_todo_application/transfer"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat          string
+	exportOutput          string
+	exportIncludeArchived bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all tasks as JSON, CSV or todo.txt",
+	Long: `Write every task, including completed and archived ones, to standard output
+or to the file given with --output. The format defaults to the output file's
+extension (.json, .csv or .txt) and falls back to JSON.
+
+JSON and CSV mark archived tasks so that "task import" puts them back in the
+archive. Use --include-archived=false to leave them out.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := transfer.FormatFromPath(exportOutput)
+		if exportFormat != "" {
+			var err error
+			if format, err = transfer.ParseFormat(exportFormat); err != nil {
+				return err
+			}
+		}
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		tasks, err := store.GetAllTasks()
+		if err != nil {
+			return fmt.Errorf("failed to get tasks: %w", err)
+		}
+		if exportIncludeArchived {
+			archived, err := store.GetArchivedTasks()
+			if err != nil {
+				return fmt.Errorf("failed to get archived tasks: %w", err)
+			}
+			tasks = append(tasks, archived...)
+			sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+		}
+
+		var out io.Writer = os.Stdout
+		if exportOutput != "" && exportOutput != "-" {
+			file, err := os.Create(exportOutput)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if err := transfer.Encode(out, format, tasks); err != nil {
+			return fmt.Errorf("failed to export tasks: %w", err)
+		}
+
+		if out != os.Stdout {
+			fmt.Printf("Exported %d tasks to %s.\n", len(tasks), exportOutput)
+		}
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "", "output format: json, csv or todotxt")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "write to this file instead of standard output")
+	exportCmd.Flags().BoolVar(&exportIncludeArchived, "include-archived", true, "include archived tasks")
+}
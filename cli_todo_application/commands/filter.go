@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// taskFilter holds the flags that narrow a command to tasks with given tags,
+// a minimum priority or a due date.
+type taskFilter struct {
+	tags     []string
+	priority string
+	due      string
+	overdue  bool
+}
+
+// register adds the filter flags to cmd; verb completes their help text, as
+// in "only <verb> tasks with this tag".
+func (f *taskFilter) register(cmd *cobra.Command, verb string) {
+	cmd.Flags().StringSliceVarP(&f.tags, "tag", "t", nil, "only "+verb+" tasks with this tag (repeatable)")
+	cmd.Flags().StringVarP(&f.priority, "priority", "p", "", "only "+verb+" tasks with at least this priority")
+	cmd.Flags().StringVar(&f.due, "due", "", "only "+verb+" tasks due on or before this date")
+	cmd.Flags().BoolVar(&f.overdue, "overdue", false, "only "+verb+" overdue tasks")
+}
+
+// isSet reports whether any filter flag was given.
+func (f *taskFilter) isSet() bool {
+	return len(f.tags) > 0 || f.priority != "" || f.due != "" || f.overdue
+}
+
+// resolve returns the storage filter selected by the flags.
+func (f *taskFilter) resolve() (storage.TaskFilter, error) {
+	filter := storage.TaskFilter{Tags: f.tags, Overdue: f.overdue}
+
+	minPriority, err := storage.ParsePriority(f.priority)
+	if err != nil {
+		return filter, err
+	}
+	filter.MinPriority = minPriority
+
+	if f.due != "" {
+		due, err := parseDate(f.due)
+		if err != nil {
+			return filter, err
+		}
+		dueBefore := due.AddDate(0, 0, 1)
+		filter.DueBefore = &dueBefore
+	}
+	return filter, nil
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"cli_todo_application/transfer"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFormat string
+	importDryRun bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import tasks from JSON, CSV or todo.txt",
+	Long: `Add the tasks from a file (or standard input when the file is "-") to your
+TODO list. The format defaults to the file's extension (.json, .csv or
+.txt) and falls back to JSON.
+
+Tasks whose description and completion state match an existing task,
+archived ones included, are skipped. Tasks without a project go into the
+selected project, and completed tasks marked as archived go back into the
+archive. Use
+--dry-run to see what would be imported without changing anything; a real
+import can be reverted with "task undo".`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+
+		format := transfer.FormatFromPath(path)
+		if importFormat != "" {
+			var err error
+			if format, err = transfer.ParseFormat(importFormat); err != nil {
+				return err
+			}
+		}
+
+		var in io.Reader = os.Stdin
+		if path != "-" {
+			file, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open input file: %w", err)
+			}
+			defer file.Close()
+			in = file
+		}
+
+		tasks, err := transfer.Decode(in, format)
+		if err != nil {
+			return fmt.Errorf("failed to read tasks: %w", err)
+		}
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		project, err := selectedProject(store)
+		if err != nil {
+			return err
+		}
+		for i := range tasks {
+			if tasks[i].Project == "" {
+				tasks[i].Project = project
+			}
+		}
+
+		result, err := store.ImportTasks(tasks, importDryRun)
+		if err != nil {
+			return fmt.Errorf("failed to import tasks: %w", err)
+		}
+
+		verb := "Imported"
+		if importDryRun {
+			verb = "Would import"
+		}
+		for _, task := range result.Added {
+			fmt.Printf("+ %s\n", task.Description)
+		}
+		for _, task := range result.Skipped {
+			fmt.Printf("= %s (duplicate)\n", task.Description)
+		}
+		fmt.Printf("%s %d tasks, skipped %d duplicates.\n", verb, len(result.Added), len(result.Skipped))
+
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVarP(&importFormat, "format", "f", "", "input format: json, csv or todotxt")
+	importCmd.Flags().BoolVarP(&importDryRun, "dry-run", "n", false, "show what would be imported without saving")
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a task list for the current directory",
+	Long: `Create .task/tasks.db in the current directory. Commands run in this
+directory or any directory below it will use this task list instead of
+the one in your home directory, so a repository can carry its own tasks.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+
+		dbPath := storage.LocalDatabasePath(dir)
+		if _, err := os.Stat(dbPath); err == nil {
+			return fmt.Errorf("a task list already exists at %s", dbPath)
+		}
+
+		store, err := storage.NewTaskStore(dbPath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		fmt.Printf("Created a task list at %s.\n", dbPath)
+		return nil
+	},
+}
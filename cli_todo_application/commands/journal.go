@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	journalLimit int
+	journalTrim  int
+)
+
+var journalCmd = &cobra.Command{
+	Use:   "journal",
+	Short: "Show the history of changes to your TODO list",
+	Long: `Display the most recent changes recorded in the operation journal, newest
+first. Use --trim N to keep only the newest N entries; changes that are
+trimmed can no longer be undone.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		if cmd.Flags().Changed("trim") {
+			removed, err := store.TrimJournal(journalTrim)
+			if err != nil {
+				return fmt.Errorf("failed to trim journal: %w", err)
+			}
+			fmt.Printf("Removed %d journal entries.\n", removed)
+			return nil
+		}
+
+		entries, err := store.GetJournal(journalLimit)
+		if err != nil {
+			return fmt.Errorf("failed to read journal: %w", err)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("The journal is empty.")
+			return nil
+		}
+
+		for _, entry := range entries {
+			status := ""
+			if entry.Undone {
+				status = " (undone)"
+			}
+			fmt.Printf("%d. %s %s%s\n", entry.ID, entry.Time.Format("2006-01-02 15:04"), entry.Summary(), status)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	journalCmd.Flags().IntVarP(&journalLimit, "limit", "l", 20, "number of entries to show (0 for all)")
+	journalCmd.Flags().IntVar(&journalTrim, "trim", 0, "keep only the newest N entries")
+}
@@ -3,36 +3,166 @@ package commands
 import (
 	"cli_todo_application/storage"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	listFilter   taskFilter
+	listSort     string
+	listNumbered bool
+	listAll      bool
+	listFlat     bool
+)
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all of your incomplete tasks",
-	Long:  `Display all tasks that are not yet completed.`,
+	Long: `Display all tasks that are not yet completed.
+
+Tasks can be filtered by tag, minimum priority or due date and sorted by
+id, due, priority or created. Only the selected project is shown unless
+--all is given. Each task is shown with its persistent ID, which "task do"
+and "task rm" accept. Use --positions to show positions in the project's
+unfiltered list instead, for use with "task do --position".
+
+Subtasks are indented below their parent unless --flat is given, and tasks
+waiting on other open tasks are marked as blocked. The task whose timer is
+running (see "task start") is marked as well.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		store, err := storage.NewTaskStore()
+		filter, err := listFilter.resolve()
+		if err != nil {
+			return err
+		}
+
+		store, err := openService()
 		if err != nil {
 			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
 		defer store.Close()
 
-		tasks, err := store.GetIncompleteTasks()
+		project, err := selectedProject(store)
+		if err != nil {
+			return err
+		}
+
+		var tasks []storage.Task
+		if listAll {
+			tasks, err = store.GetIncompleteTasks()
+		} else {
+			tasks, err = store.GetProjectTasks(project)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to get tasks: %w", err)
 		}
 
+		positions := make(map[int]int, len(tasks))
+		perProject := make(map[string]int)
+		for _, task := range tasks {
+			perProject[task.ProjectName()]++
+			positions[task.ID] = perProject[task.ProjectName()]
+		}
+
 		if len(tasks) == 0 {
 			fmt.Println("You have no incomplete tasks!")
 			return nil
 		}
 
-		fmt.Println("You have the following tasks:")
-		for i, task := range tasks {
-			fmt.Printf("%d. %s\n", i+1, task.Description)
+		tasks = storage.FilterTasks(tasks, filter)
+		if err := storage.SortTasks(tasks, listSort); err != nil {
+			return err
+		}
+
+		if len(tasks) == 0 {
+			fmt.Println("No tasks match the given filters.")
+			return nil
+		}
+
+		open, err := store.GetIncompleteTasks()
+		if err != nil {
+			return fmt.Errorf("failed to get tasks: %w", err)
+		}
+		openIDs := storage.OpenTaskIDs(open)
+
+		nodes := storage.TaskTree(tasks)
+		if listFlat {
+			nodes = make([]storage.TreeNode, len(tasks))
+			for i, task := range tasks {
+				nodes[i] = storage.TreeNode{Task: task}
+			}
+		}
+
+		if listAll {
+			fmt.Println("You have the following tasks:")
+		} else {
+			fmt.Printf("You have the following tasks in %s:\n", project)
+		}
+		for _, node := range nodes {
+			task := node.Task
+			number := task.ID
+			if listNumbered {
+				number = positions[task.ID]
+			}
+			details := formatTaskDetails(task)
+			if blockers := task.OpenBlockers(openIDs); len(blockers) > 0 {
+				details += " (blocked by " + joinIDs(blockers) + ")"
+			}
+			if listAll {
+				details += " (in " + task.ProjectName() + ")"
+			}
+			indent := strings.Repeat("   ", node.Depth)
+			fmt.Printf("%s%d. %s%s\n", indent, number, task.Description, details)
 		}
 
 		return nil
 	},
 }
+
+// formatTaskDetails renders priority, due date and tags as a suffix for list output.
+func formatTaskDetails(task storage.Task) string {
+	var details []string
+
+	if task.Priority != storage.PriorityNone {
+		details = append(details, "["+task.Priority.String()+"]")
+	}
+	if task.Due != nil {
+		due := "due " + task.Due.Format(dateLayout)
+		if task.IsOverdue(time.Now()) {
+			due = "OVERDUE, " + due
+		}
+		details = append(details, "("+due+")")
+	}
+	if task.Recurrence != nil {
+		details = append(details, "(repeats "+task.Recurrence.String()+")")
+	}
+	for _, tag := range task.Tags {
+		details = append(details, "+"+tag)
+	}
+	if entry := task.Running(); entry != nil {
+		details = append(details, "(RUNNING for "+formatDuration(entry.Duration(time.Now()))+")")
+	}
+
+	if len(details) == 0 {
+		return ""
+	}
+	return " " + strings.Join(details, " ")
+}
+
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	listFilter.register(listCmd, "show")
+	listCmd.Flags().StringVarP(&listSort, "sort", "s", "id", "sort by id, due, priority or created")
+	listCmd.Flags().BoolVarP(&listNumbered, "positions", "n", false, "number tasks by position instead of ID")
+	listCmd.Flags().BoolVarP(&listAll, "all", "a", false, "show tasks from every project")
+	listCmd.Flags().BoolVar(&listFlat, "flat", false, "do not indent subtasks below their parents")
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var moveByPosition bool
+
+var moveCmd = &cobra.Command{
+	Use:   "move [task id | description prefix] [project]",
+	Short: "Move a task to another project",
+	Long: `Move a task into another project, creating the project if it does not
+exist yet. Use --position to pass the task's position in the selected
+project's list instead of its ID.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		target, err := resolveTaskRef(store, args[0], moveByPosition)
+		if err != nil {
+			return err
+		}
+
+		task, err := store.MoveTask(target.ID, args[1])
+		if err != nil {
+			return fmt.Errorf("failed to move task: %w", err)
+		}
+
+		fmt.Printf("Moved \"%s\" to %s.\n", task.Description, task.ProjectName())
+		return nil
+	},
+}
+
+func init() {
+	moveCmd.Flags().BoolVarP(&moveByPosition, "position", "n", false, "treat the argument as a position in the selected project")
+}
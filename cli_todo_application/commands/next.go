@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var nextLimit int
+
+var nextCmd = &cobra.Command{
+	Use:   "next",
+	Short: "Suggest what to work on next",
+	Long: `Show the open tasks in the selected project that can be started right
+away: tasks that are not blocked by other open tasks and have no open
+subtasks. Overdue tasks come first, then higher priority, earlier due
+dates and older tasks.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		project, err := selectedProject(store)
+		if err != nil {
+			return err
+		}
+
+		tasks, err := store.NextTasks(project, nextLimit)
+		if err != nil {
+			return fmt.Errorf("failed to get next tasks: %w", err)
+		}
+
+		if len(tasks) == 0 {
+			fmt.Println("There is nothing you can start on right now.")
+			return nil
+		}
+
+		fmt.Println("You could work on:")
+		for _, task := range tasks {
+			fmt.Printf("%d. %s%s\n", task.ID, task.Description, formatTaskDetails(task))
+		}
+		return nil
+	},
+}
+
+func init() {
+	nextCmd.Flags().IntVarP(&nextLimit, "limit", "l", 3, "number of suggestions (0 for all)")
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var projectsDefault string
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "List your projects",
+	Long: `Display every project with its number of open and total tasks. The
+default project, marked with *, receives new tasks and is listed when
+--project is not given; change it with --set-default.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		if projectsDefault != "" {
+			if err := store.SetDefaultProject(projectsDefault); err != nil {
+				return fmt.Errorf("failed to set default project: %w", err)
+			}
+			project, err := store.DefaultProject()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("The default project is now %s.\n", project)
+			return nil
+		}
+
+		projects, err := store.GetProjects()
+		if err != nil {
+			return fmt.Errorf("failed to get projects: %w", err)
+		}
+
+		for _, project := range projects {
+			marker := " "
+			if project.Default {
+				marker = "*"
+			}
+			fmt.Printf("%s %s (%d open, %d total)\n", marker, project.Name, project.Open, project.Total)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	projectsCmd.Flags().StringVar(&projectsDefault, "set-default", "", "make this the default project")
+}
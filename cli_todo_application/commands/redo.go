@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var redoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Redo the last undone change",
+	Long: `Reapply a change that was reverted with "task undo". Redo is only possible
+until a new change is made to your tasks.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		entry, err := store.Redo()
+		if errors.Is(err, storage.ErrNothingToRedo) {
+			fmt.Println("There is nothing to redo.")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to redo: %w", err)
+		}
+
+		fmt.Printf("Redid %s.\n", entry.Summary())
+		return nil
+	},
+}
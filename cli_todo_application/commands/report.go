@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const untaggedLabel = "(untagged)"
+
+var (
+	reportRange dateRange
+	reportBy    string
+	reportCSV   bool
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize tracked time per task, tag and day",
+	Long: `Summarize the time tracked with "task start" and "task stop" over the
+last week (or the range given with --since/--until, --week or --month).
+Time is split at midnight, and a running timer counts up to now.
+
+Use --by to show only one of the task, tag or day summaries. With --csv the
+report is written as CSV instead: the chosen summary as key and hours, or
+without --by one row per task and day, which suits billing spreadsheets.
+A task with several tags counts towards each of them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch reportBy {
+		case "", "task", "tag", "day":
+		default:
+			return fmt.Errorf("invalid grouping: %q (must be one of task, tag, day)", reportBy)
+		}
+
+		from, to, err := reportRange.resolve(7)
+		if err != nil {
+			return err
+		}
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		tasks, err := store.GetAllTasks()
+		if err != nil {
+			return fmt.Errorf("failed to get tasks: %w", err)
+		}
+		archived, err := store.GetArchivedTasks()
+		if err != nil {
+			return fmt.Errorf("failed to get archived tasks: %w", err)
+		}
+		tasks = append(tasks, archived...)
+
+		spans := storage.WorkSpans(tasks, from, to, time.Now())
+
+		if reportCSV {
+			if reportBy == "" {
+				return writeSpansCSV(spans)
+			}
+			return writeTotalsCSV(reportBy, timeTotals(spans, reportBy))
+		}
+
+		if len(spans) == 0 {
+			fmt.Printf("You have not tracked any time %s.\n", describeRange(from, to))
+			return nil
+		}
+
+		fmt.Printf("Time tracked %s:\n", describeRange(from, to))
+		for _, by := range []string{"task", "tag", "day"} {
+			if reportBy != "" && reportBy != by {
+				continue
+			}
+			fmt.Printf("\nBy %s:\n", by)
+			for _, total := range timeTotals(spans, by) {
+				fmt.Printf("  %-40s %s\n", total.key, formatDuration(total.duration))
+			}
+		}
+		return nil
+	},
+}
+
+type timeTotal struct {
+	key      string
+	duration time.Duration
+}
+
+// timeTotals adds up spans per task, tag or day. Tasks and tags are ordered by
+// time spent, days chronologically.
+func timeTotals(spans []storage.WorkSpan, by string) []timeTotal {
+	sums := make(map[string]time.Duration)
+	for _, span := range spans {
+		var keys []string
+		switch by {
+		case "task":
+			keys = []string{fmt.Sprintf("%d. %s", span.Task.ID, span.Task.Description)}
+		case "tag":
+			keys = span.Task.Tags
+			if len(keys) == 0 {
+				keys = []string{untaggedLabel}
+			}
+		case "day":
+			keys = []string{span.Day.Format(dateLayout)}
+		}
+		for _, key := range keys {
+			sums[key] += span.Duration
+		}
+	}
+
+	totals := make([]timeTotal, 0, len(sums))
+	for key, duration := range sums {
+		totals = append(totals, timeTotal{key: key, duration: duration})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if by != "day" && totals[i].duration != totals[j].duration {
+			return totals[i].duration > totals[j].duration
+		}
+		return totals[i].key < totals[j].key
+	})
+	return totals
+}
+
+func writeSpansCSV(spans []storage.WorkSpan) error {
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write([]string{"day", "task_id", "description", "project", "tags", "hours"})
+	for _, span := range spans {
+		writer.Write([]string{
+			span.Day.Format(dateLayout),
+			strconv.Itoa(span.Task.ID),
+			span.Task.Description,
+			span.Task.ProjectName(),
+			strings.Join(span.Task.Tags, ";"),
+			formatHours(span.Duration),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeTotalsCSV(by string, totals []timeTotal) error {
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write([]string{by, "hours"})
+	for _, total := range totals {
+		writer.Write([]string{total.key, formatHours(total.duration)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatHours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+}
+
+func init() {
+	reportRange.register(reportCmd)
+	reportCmd.Flags().StringVar(&reportBy, "by", "", "only summarize by task, tag or day")
+	reportCmd.Flags().BoolVar(&reportCSV, "csv", false, "write the report as CSV")
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"fmt"
+	"strconv"
+)
+
+// resolveTaskRef turns a command-line reference into a task. By default ref is a
+// persistent task ID or a unique description prefix; with byPosition it is the
+// 1-based number shown by "task list --positions" within the selected project.
+func resolveTaskRef(store taskService, ref string, byPosition bool) (*storage.Task, error) {
+	if !byPosition {
+		return store.ResolveTask(ref)
+	}
+
+	position, err := strconv.Atoi(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid task number: %s", ref)
+	}
+	if position < 1 {
+		return nil, fmt.Errorf("task number must be greater than 0")
+	}
+	project, err := selectedProject(store)
+	if err != nil {
+		return nil, err
+	}
+	return store.TaskAtPosition(project, position)
+}
+
+// taskSelection builds the selection for a batch command from its arguments
+// and filter flags, within the selected project. Refs are resolved by the
+// store in the same transaction as the change itself.
+func taskSelection(store taskService, refs []string, byPosition bool, filter *taskFilter) (storage.Selection, error) {
+	sel := storage.Selection{Refs: refs, ByPosition: byPosition}
+
+	project, err := selectedProject(store)
+	if err != nil {
+		return sel, err
+	}
+	sel.Project = project
+
+	if filter.isSet() {
+		f, err := filter.resolve()
+		if err != nil {
+			return sel, err
+		}
+		sel.Filter = &f
+	}
+	return sel, nil
+}
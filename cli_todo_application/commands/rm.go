@@ -1,40 +1,63 @@
 package commands
 
 import (
-	"cli_todo_application/storage"
 	"fmt"
-	"strconv"
+	"math"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	rmByPosition bool
+	rmFilter     taskFilter
+)
+
 var rmCmd = &cobra.Command{
-	Use:   "rm [task number]",
-	Short: "Remove a task from your TODO list",
-	Long:  `Remove a task from your TODO list by providing its number from the list.`,
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		taskNum, err := strconv.Atoi(args[0])
-		if err != nil {
-			return fmt.Errorf("invalid task number: %s", args[0])
-		}
+	Use:   "rm [task id | range | description prefix]...",
+	Short: "Remove tasks from your TODO list",
+	Long: `Remove tasks from your TODO list by providing their IDs from the list,
+ranges of IDs such as 5-7, or unique prefixes of their descriptions. Use
+--position to pass positions (and ranges of positions) in the incomplete
+list instead of IDs. The filter flags remove every matching task in the
+selected project, or narrow down the tasks given as arguments.
 
-		if taskNum < 1 {
-			return fmt.Errorf("task number must be greater than 0")
+All tasks are removed together or not at all, and positions refer to the
+list as it was before the command ran. Use "task undo" to bring them back.`,
+	ValidArgsFunction: completeTaskRefs(&rmByPosition, math.MaxInt),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !rmFilter.isSet() {
+			return fmt.Errorf("no task given (pass task IDs, ranges, description prefixes or filters)")
 		}
 
-		store, err := storage.NewTaskStore()
+		store, err := openService()
 		if err != nil {
 			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
 		defer store.Close()
 
-		task, err := store.DeleteTask(taskNum)
+		sel, err := taskSelection(store, args, rmByPosition, &rmFilter)
 		if err != nil {
-			return fmt.Errorf("failed to delete task: %w", err)
+			return err
 		}
 
-		fmt.Printf("You have deleted the \"%s\" task.\n", task.Description)
+		tasks, err := store.DeleteTasks(sel)
+		if err != nil {
+			return fmt.Errorf("failed to delete tasks: %w", err)
+		}
+
+		if len(tasks) == 0 {
+			fmt.Println("No tasks match the given filters.")
+			return nil
+		}
+
+		for _, task := range tasks {
+			fmt.Printf("You have deleted the \"%s\" task.\n", task.Description)
+		}
 		return nil
 	},
-}
\ No newline at end of file
+}
+
+func init() {
+	rmCmd.Flags().BoolVarP(&rmByPosition, "position", "n", false, "treat the arguments as positions in the incomplete list")
+	rmFilter.register(rmCmd, "delete")
+}
@@ -1,26 +1,143 @@
 package commands
 
 import (
+	"cli_todo_application/api"
+	"cli_todo_application/storage"
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
 )
 
+var (
+	projectFlag string
+	dbFlag      string
+)
+
 var rootCmd = &cobra.Command{
-	Use: "task",
+	Use:   "task",
 	Short: "A CLI for managing your TODOS",
 	Long: `task is a CLI for managing your TODOs.
 
 This application allows you to add, list, complete, and manage your tasks
-from the command line with persistent storage.`,
+from the command line with persistent storage.
+
+Tasks are stored in ~/.task/tasks.db unless a .task/tasks.db exists in the
+current directory or one of its parents (see "task init"). The TASK_DB
+environment variable and the --db flag override both.
+
+Shell completion scripts, which complete task IDs for do, rm and edit,
+are printed by "task completion bash|zsh|fish|powershell".
+
+While "task serve" is running, every command except init goes through the
+server instead of opening the database.`,
+}
+
+// taskService is the store as the commands use it. It is implemented by
+// *storage.TaskStore and by *api.Client, which talks to a running
+// "task serve".
+type taskService interface {
+	AddTask(template storage.Task) (*storage.Task, error)
+	GetTask(id int) (*storage.Task, error)
+	ResolveTask(ref string) (*storage.Task, error)
+	TaskAtPosition(project string, position int) (*storage.Task, error)
+	GetIncompleteTasks() ([]storage.Task, error)
+	GetProjectTasks(project string) ([]storage.Task, error)
+	CompleteTask(id int, force bool) (*storage.Task, error)
+	DeleteTask(id int) (*storage.Task, error)
+	CompleteTasks(sel storage.Selection, force bool) ([]storage.Task, error)
+	DeleteTasks(sel storage.Selection) ([]storage.Task, error)
+	GetCompletedTasks(from, to time.Time) ([]storage.Task, error)
+	EditTask(id int, edit storage.TaskEdit) (*storage.Task, error)
+	MoveTask(id int, project string) (*storage.Task, error)
+	AddDependency(id, blockerID int) (*storage.Task, error)
+	RemoveDependency(id, blockerID int) (*storage.Task, error)
+	StartTask(id int) (task, stopped *storage.Task, err error)
+	StopTask() (*storage.Task, error)
+	NextTasks(project string, limit int) ([]storage.Task, error)
+	SearchTasks(pattern string, regex bool) ([]storage.Task, error)
+	GetAllTasks() ([]storage.Task, error)
+	GetArchivedTasks() ([]storage.Task, error)
+	ArchiveTasks(cutoff time.Time, dryRun bool) ([]storage.Task, error)
+	PurgeArchive(cutoff time.Time, dryRun bool) ([]storage.Task, error)
+	ImportTasks(tasks []storage.Task, dryRun bool) (*storage.ImportResult, error)
+	Undo() (*storage.JournalEntry, error)
+	Redo() (*storage.JournalEntry, error)
+	GetJournal(limit int) ([]storage.JournalEntry, error)
+	TrimJournal(keep int) (int, error)
+	GetProjects() ([]storage.ProjectInfo, error)
+	DefaultProject() (string, error)
+	SetDefaultProject(project string) error
+	SyncDir() (string, error)
+	SetSyncDir(dir string) error
+	Sync(dir string) (*storage.SyncResult, error)
+	Close() error
 }
 
 func Execute() error {
 	return rootCmd.Execute()
 }
 
+// openService connects to the "task serve" instance holding the selected
+// database (--db, $TASK_DB or the nearest .task/tasks.db) when one is
+// running, and opens the database directly otherwise.
+func openService() (taskService, error) {
+	dbPath, err := storage.ResolveDatabasePath(dbFlag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database path: %w", err)
+	}
+
+	if client, running := api.Discover(dbPath); running {
+		return client, nil
+	}
+
+	store, err := storage.NewTaskStore(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// selectedProject returns the project given with --project, or the store's
+// default project when the flag is not set.
+func selectedProject(store taskService) (string, error) {
+	if projectFlag != "" {
+		return storage.NormalizeProject(projectFlag)
+	}
+	return store.DefaultProject()
+}
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&dbFlag, "db", "", "path to the task database (overrides $TASK_DB)")
+	rootCmd.PersistentFlags().StringVarP(&projectFlag, "project", "P", "", "project to operate on (defaults to the configured default project)")
+
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(doCmd)
 	rootCmd.AddCommand(rmCmd)
 	rootCmd.AddCommand(completedCmd)
-}
\ No newline at end of file
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(redoCmd)
+	rootCmd.AddCommand(journalCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(moveCmd)
+	rootCmd.AddCommand(projectsCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(editCmd)
+	rootCmd.AddCommand(blockCmd)
+	rootCmd.AddCommand(unblockCmd)
+	rootCmd.AddCommand(nextCmd)
+	rootCmd.AddCommand(uiCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(agendaCmd)
+	rootCmd.AddCommand(daemonCmd)
+	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(purgeCmd)
+}
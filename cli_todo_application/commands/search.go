@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchRegex bool
+	searchOpen  bool
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search tasks by description or tag",
+	Long: `Find tasks whose description or tags contain the query, ignoring case.
+Completed tasks are included unless --open is given. With --regex the
+query is a regular expression; prefix it with (?i) to ignore case.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := strings.Join(args, " ")
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		tasks, err := store.SearchTasks(query, searchRegex)
+		if err != nil {
+			return fmt.Errorf("failed to search tasks: %w", err)
+		}
+
+		found := 0
+		for _, task := range tasks {
+			if searchOpen && task.Completed {
+				continue
+			}
+
+			status := " "
+			if task.Completed {
+				status = "x"
+			}
+			fmt.Printf("[%s] %d. %s%s (in %s)%s\n", status, task.ID, task.Description, formatTaskDetails(task), task.ProjectName(), archivedLabel(task))
+			found++
+		}
+
+		if found == 0 {
+			fmt.Printf("No tasks match %q.\n", query)
+		}
+		return nil
+	},
+}
+
+func init() {
+	searchCmd.Flags().BoolVarP(&searchRegex, "regex", "e", false, "treat the query as a regular expression")
+	searchCmd.Flags().BoolVarP(&searchOpen, "open", "o", false, "only show incomplete tasks")
+}
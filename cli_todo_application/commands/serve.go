@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"cli_todo_application/api"
+	"cli_todo_application/storage"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr  string
+	serveToken string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve your tasks as a JSON API on localhost",
+	Long: `Serve the task database as a REST JSON API so that editor plugins and
+dashboards can read and change the same tasks as the CLI:
+
+  GET    /tasks                  incomplete tasks (?project=name or ?all=true)
+  POST   /tasks                  add a task from a JSON body
+  GET    /tasks/{id}             a task by ID or unique description prefix
+  POST   /tasks/{id}/complete    complete a task (?force=true for subtasks)
+  DELETE /tasks/{id}             delete a task
+  POST   /tasks/complete         complete a selection of tasks in one go
+                                 (?force=true for subtasks)
+  POST   /tasks/delete           delete a selection of tasks in one go
+  GET    /completed              completed tasks (?from= and ?to=, RFC 3339)
+  GET    /projects/default       the default project
+
+The batch routes take a JSON selection body and change either every
+selected task or none of them:
+
+  {"refs": ["3", "5-7", "buy"], "by_position": false, "project": "work",
+   "filter": {"tags": ["home"], "min_priority": "high",
+              "due_before": "2024-06-01T00:00:00Z", "overdue": false}}
+
+refs are IDs, ID ranges and description prefixes, or positions in project
+with by_position. filter keeps only the matching tasks; without refs it
+selects the matching open tasks in project. Every field is optional, and
+project defaults to the default project.
+
+Every other command has a route too, for editing, moving, blocking, timing,
+searching, archiving, importing, undoing and syncing; see the api package
+documentation for the full list.
+
+Requests must send "Authorization: Bearer <token>". The token is taken from
+--token or $TASK_API_TOKEN, or else generated, and is written with the
+server's address to a file next to the database (tasks.db.server), readable
+only by you, where the CLI and other clients find it.
+
+The server only listens on loopback addresses and refuses requests for other
+host names, requests from web pages on other sites, and request bodies that
+are not sent as application/json.
+
+The server keeps the database open, so the other task commands talk to it
+automatically while it runs. Stop it with Ctrl-C.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host, _, err := net.SplitHostPort(serveAddr)
+		if err != nil {
+			return fmt.Errorf("invalid address: %w", err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("invalid address: %s is not a loopback address", serveAddr)
+		}
+
+		token := serveToken
+		if token == "" {
+			token = os.Getenv(api.TokenEnvVar)
+		}
+		if token == "" {
+			if token, err = api.NewToken(); err != nil {
+				return err
+			}
+		}
+
+		dbPath, err := storage.ResolveDatabasePath(dbFlag)
+		if err != nil {
+			return fmt.Errorf("failed to get database path: %w", err)
+		}
+		store, err := storage.NewTaskStore(dbPath)
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		listener, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+
+		info := api.ServerInfo{Addr: listener.Addr().String(), Token: token, PID: os.Getpid()}
+		if err := api.WriteInfo(dbPath, info); err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to write server info: %w", err)
+		}
+		defer api.RemoveInfo(dbPath)
+
+		server := &http.Server{
+			Handler:           api.NewServer(store, token),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- server.Serve(listener)
+		}()
+
+		fmt.Printf("Serving %s on http://%s\n", dbPath, info.Addr)
+
+		select {
+		case err := <-errs:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("server failed: %w", err)
+			}
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				return fmt.Errorf("failed to shut down server: %w", err)
+			}
+			fmt.Println("Server stopped.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7878", "loopback address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "bearer token clients must send (defaults to $TASK_API_TOKEN, or a generated one)")
+}
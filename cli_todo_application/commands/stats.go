@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statsRange dateRange
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show completion statistics",
+	Long: `Report how many tasks were completed on each day of the last week (or the
+range given with --since/--until, --week or --month), the average time
+from creating a task to completing it, and your current streak of days
+with at least one completed task.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, to, err := statsRange.resolve(7)
+		if err != nil {
+			return err
+		}
+
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		history, err := store.GetCompletedTasks(time.Time{}, time.Time{})
+		if err != nil {
+			return fmt.Errorf("failed to get completed tasks: %w", err)
+		}
+
+		if from.IsZero() {
+			if len(history) == 0 {
+				from = to.AddDate(0, 0, -1)
+			} else {
+				from = storage.StartOfDay(*history[0].CompletedAt)
+			}
+		}
+
+		var inRange []storage.Task
+		for _, task := range history {
+			if task.CompletedBetween(from, to) {
+				inRange = append(inRange, task)
+			}
+		}
+
+		fmt.Printf("Completed tasks %s:\n", describeRange(from, to))
+		for _, day := range storage.CompletionsPerDay(inRange, from, to) {
+			fmt.Printf("  %s %s %s %d\n", day.Day.Format(dateLayout), day.Day.Format("Mon"), strings.Repeat("#", day.Count), day.Count)
+		}
+		fmt.Printf("Total: %d\n", len(inRange))
+
+		if lead := storage.AverageLeadTime(inRange); lead > 0 {
+			fmt.Printf("Average time to complete: %s\n", formatDuration(lead))
+		}
+
+		streak := storage.CurrentStreak(history, time.Now())
+		unit := "days"
+		if streak == 1 {
+			unit = "day"
+		}
+		fmt.Printf("Current streak: %d %s\n", streak, unit)
+
+		return nil
+	},
+}
+
+// formatDuration renders d as days, hours and minutes, e.g. "2d 3h 15m".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+func init() {
+	statsRange.register(statsCmd)
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+var syncDir string
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Exchange task changes with other machines through a shared directory",
+	Long: `Sync tasks with other machines through a directory kept in step by a
+file-sync tool. Each machine appends its changes to its own log in the
+directory and merges the logs of the others.
+
+Changes are merged field by field and the most recent change to a field
+wins. Fields changed on both sides since the last sync are reported as
+conflicts. Deleting a task wins over editing it.
+
+Pass --dir once to choose the directory; later syncs reuse it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		dir := syncDir
+		if dir == "" {
+			if dir, err = store.SyncDir(); err != nil {
+				return fmt.Errorf("failed to get sync directory: %w", err)
+			}
+			if dir == "" {
+				return fmt.Errorf("no sync directory configured (use --dir)")
+			}
+		} else {
+			if dir, err = homedir.Expand(dir); err != nil {
+				return fmt.Errorf("invalid sync directory: %w", err)
+			}
+			if dir, err = filepath.Abs(dir); err != nil {
+				return fmt.Errorf("invalid sync directory: %w", err)
+			}
+			if err := store.SetSyncDir(dir); err != nil {
+				return fmt.Errorf("failed to save sync directory: %w", err)
+			}
+		}
+
+		result, err := store.Sync(dir)
+		if err != nil {
+			return fmt.Errorf("failed to sync: %w", err)
+		}
+
+		fmt.Printf("Synced with %s as %s: sent %d changes, received %d.\n", dir, result.Machine, result.Sent, result.Received)
+		for _, task := range result.Added {
+			fmt.Printf("Added %d. %s\n", task.ID, task.Description)
+		}
+		for _, task := range result.Updated {
+			fmt.Printf("Updated %d. %s\n", task.ID, task.Description)
+		}
+		for _, task := range result.Deleted {
+			fmt.Printf("Deleted %d. %s\n", task.ID, task.Description)
+		}
+		for _, conflict := range result.Conflicts {
+			kept, lost := conflict.Local, conflict.Remote
+			winner := "this machine"
+			if conflict.RemoteWon {
+				kept, lost = conflict.Remote, conflict.Local
+				winner = conflict.RemoteMachine
+			}
+			if conflict.Deleted {
+				fmt.Printf("Conflict on %q: deleted on %s while its %s changed; kept the deletion.\n",
+					conflict.Task.Description, winner, conflict.Field)
+				continue
+			}
+			fmt.Printf("Conflict on %q: %s changed on both machines; kept %s from %s over %s.\n",
+				conflict.Task.Description, conflict.Field, kept, winner, lost)
+		}
+		return nil
+	},
+}
+
+func init() {
+	syncCmd.Flags().StringVar(&syncDir, "dir", "", "shared directory holding the change logs (remembered)")
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var startByPosition bool
+
+var startCmd = &cobra.Command{
+	Use:   "start [task id | description prefix]",
+	Short: "Start tracking time on a task",
+	Long: `Start the timer on a task. Only one task is timed at a time, so a timer
+running on another task is stopped first. Completing a task stops its
+timer. See "task report" for the tracked time.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		target, err := resolveTaskRef(store, args[0], startByPosition)
+		if err != nil {
+			return err
+		}
+
+		task, stopped, err := store.StartTask(target.ID)
+		if err != nil {
+			return fmt.Errorf("failed to start task: %w", err)
+		}
+
+		if stopped != nil {
+			printStopped(stopped)
+		}
+		fmt.Printf("Started working on \"%s\".\n", task.Description)
+		return nil
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop tracking time on the running task",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		task, err := store.StopTask()
+		if errors.Is(err, storage.ErrNoRunningTask) {
+			fmt.Println("No task is running.")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stop task: %w", err)
+		}
+
+		printStopped(task)
+		return nil
+	},
+}
+
+func printStopped(task *storage.Task) {
+	entry := task.TimeEntries[len(task.TimeEntries)-1]
+	now := time.Now()
+	fmt.Printf("Stopped working on \"%s\" after %s (%s in total).\n",
+		task.Description, formatDuration(entry.Duration(now)), formatDuration(task.Tracked(now)))
+}
+
+func init() {
+	startCmd.Flags().BoolVarP(&startByPosition, "position", "n", false, "treat the argument as a position in the incomplete list")
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"cli_todo_application/tui"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse and edit tasks in a full-screen interface",
+	Long: `Open an interactive, full-screen view of the selected project's tasks.
+Move with the arrow keys (or j/k), then press x to complete, d to delete,
+e to edit, a to add, c to toggle completed tasks, / to filter, u and r to
+undo and redo, and q to quit. Every change is saved immediately.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		project, err := selectedProject(store)
+		if err != nil {
+			return err
+		}
+
+		return tui.Run(store, project)
+	},
+}
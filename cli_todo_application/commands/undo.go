@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"cli_todo_application/storage"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the last change to your TODO list",
+	Long: `Revert the most recent add, complete, remove or other change made to your
+tasks. Run it repeatedly to step further back through the journal.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		defer store.Close()
+
+		entry, err := store.Undo()
+		if errors.Is(err, storage.ErrNothingToUndo) {
+			fmt.Println("There is nothing to undo.")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to undo: %w", err)
+		}
+
+		fmt.Printf("Undid %s.\n", entry.Summary())
+		return nil
+	},
+}
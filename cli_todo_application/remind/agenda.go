@@ -0,0 +1,89 @@
+// Package remind finds tasks that are overdue or coming due and sends
+// notifications about them.
+package remind
+
+import (
+	"cli_todo_application/storage"
+	"sort"
+	"time"
+)
+
+// Kind classifies a task on the agenda.
+type Kind string
+
+const (
+	KindOverdue  Kind = "overdue"
+	KindDueToday Kind = "due-today"
+	KindDueSoon  Kind = "due-soon"
+)
+
+// Agenda groups incomplete tasks with a due date by urgency. Each group is
+// ordered by due date, then priority.
+type Agenda struct {
+	Overdue  []storage.Task
+	DueToday []storage.Task
+	DueSoon  []storage.Task
+}
+
+// Empty reports whether nothing is on the agenda.
+func (a Agenda) Empty() bool {
+	return len(a.Overdue) == 0 && len(a.DueToday) == 0 && len(a.DueSoon) == 0
+}
+
+// BuildAgenda sorts tasks into an agenda for the day containing now. Tasks
+// due within the next days days after today are due soon; completed tasks
+// and tasks without a due date are left out.
+func BuildAgenda(tasks []storage.Task, now time.Time, days int) Agenda {
+	today := storage.StartOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	horizon := tomorrow.AddDate(0, 0, days)
+
+	var agenda Agenda
+	for _, task := range tasks {
+		if task.Completed || task.Due == nil {
+			continue
+		}
+		switch {
+		case task.IsOverdue(now):
+			agenda.Overdue = append(agenda.Overdue, task)
+		case task.Due.Before(tomorrow):
+			agenda.DueToday = append(agenda.DueToday, task)
+		case task.Due.Before(horizon):
+			agenda.DueSoon = append(agenda.DueSoon, task)
+		}
+	}
+
+	for _, group := range [][]storage.Task{agenda.Overdue, agenda.DueToday, agenda.DueSoon} {
+		sort.SliceStable(group, func(i, j int) bool {
+			if !group[i].Due.Equal(*group[j].Due) {
+				return group[i].Due.Before(*group[j].Due)
+			}
+			return group[i].Priority > group[j].Priority
+		})
+	}
+	return agenda
+}
+
+// Items lists every task on the agenda with its kind, most urgent first.
+func (a Agenda) Items() []Item {
+	var items []Item
+	for _, group := range []struct {
+		kind  Kind
+		tasks []storage.Task
+	}{
+		{KindOverdue, a.Overdue},
+		{KindDueToday, a.DueToday},
+		{KindDueSoon, a.DueSoon},
+	} {
+		for _, task := range group.tasks {
+			items = append(items, Item{Kind: group.kind, Task: task})
+		}
+	}
+	return items
+}
+
+// Item is one task on the agenda.
+type Item struct {
+	Kind Kind         `json:"kind"`
+	Task storage.Task `json:"task"`
+}
@@ -0,0 +1,119 @@
+package remind
+
+import (
+	"cli_todo_application/storage"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Clock tells the time. Tests can replace SystemClock with a fake to step
+// through days without waiting.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is the real wall clock.
+var SystemClock Clock = systemClock{}
+
+// TaskSource provides the incomplete tasks to check. The daemon opens a
+// source for every check and closes it straight after, so that it does not
+// hold the database lock between checks.
+type TaskSource interface {
+	GetIncompleteTasks() ([]storage.Task, error)
+	Close() error
+}
+
+// Daemon checks for overdue and due-soon tasks at a fixed interval and sends
+// a notification for each. A task is announced at most once per kind and day.
+type Daemon struct {
+	Open     func() (TaskSource, error)
+	Notifier Notifier
+	Clock    Clock
+	Interval time.Duration
+	Days     int
+	// OnError is called with errors from checks that failed; the daemon keeps
+	// running. It may be nil.
+	OnError func(error)
+
+	sent map[string]bool
+}
+
+// Run checks immediately and then every Interval until ctx is cancelled.
+func (d *Daemon) Run(ctx context.Context) error {
+	if d.Interval <= 0 {
+		return fmt.Errorf("invalid interval: %s", d.Interval)
+	}
+
+	for {
+		if err := d.Check(ctx); err != nil && d.OnError != nil {
+			d.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.Clock.After(d.Interval):
+		}
+	}
+}
+
+// Check sends a notification for every agenda item that has not been
+// announced today.
+func (d *Daemon) Check(ctx context.Context) error {
+	source, err := d.Open()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	tasks, err := source.GetIncompleteTasks()
+	source.Close()
+	if err != nil {
+		return fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	if d.sent == nil {
+		d.sent = make(map[string]bool)
+	}
+
+	now := d.Clock.Now()
+	day := storage.StartOfDay(now).Format("2006-01-02")
+	for _, item := range BuildAgenda(tasks, now, d.Days).Items() {
+		key := fmt.Sprintf("%s|%s|%s|%s", day, item.Kind, item.Task.UID, item.Task.Due.Format(time.RFC3339))
+		if d.sent[key] {
+			continue
+		}
+		if err := d.Notifier.Notify(ctx, NewNotification(item)); err != nil {
+			return fmt.Errorf("failed to send notification: %w", err)
+		}
+		d.sent[key] = true
+	}
+
+	// Forget earlier days so the set does not grow without bound.
+	for key := range d.sent {
+		if !strings.HasPrefix(key, day+"|") {
+			delete(d.sent, key)
+		}
+	}
+	return nil
+}
+
+// NewNotification describes an agenda item for a notifier.
+func NewNotification(item Item) Notification {
+	title := "Task due soon"
+	switch item.Kind {
+	case KindOverdue:
+		title = "Task overdue"
+	case KindDueToday:
+		title = "Task due today"
+	}
+
+	body := fmt.Sprintf("%d. %s (due %s)", item.Task.ID, item.Task.Description, item.Task.Due.Format("2006-01-02"))
+	return Notification{Title: title, Body: body, Item: item}
+}
@@ -0,0 +1,124 @@
+package remind
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Notification is a reminder about one task.
+type Notification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Item
+}
+
+// Notifier delivers notifications.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// ParseNotifier builds a notifier from a specification:
+//
+//	stdout               print to standard output
+//	command:CMD [ARGS]   run CMD ARGS TITLE BODY, e.g. command:notify-send
+//	webhook:URL          POST the notification as JSON to a local URL
+func ParseNotifier(spec string, stdout io.Writer) (Notifier, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "stdout":
+		if arg != "" {
+			return nil, fmt.Errorf("invalid notifier: %q takes no arguments", kind)
+		}
+		return &WriterNotifier{W: stdout}, nil
+	case "command":
+		fields := strings.Fields(arg)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid notifier: command needs a program, e.g. command:notify-send")
+		}
+		return &CommandNotifier{Name: fields[0], Args: fields[1:]}, nil
+	case "webhook":
+		return NewWebhookNotifier(arg)
+	}
+	return nil, fmt.Errorf("invalid notifier: %q (use stdout, command:CMD or webhook:URL)", spec)
+}
+
+// WriterNotifier prints notifications as single lines.
+type WriterNotifier struct {
+	W io.Writer
+}
+
+func (n *WriterNotifier) Notify(ctx context.Context, notification Notification) error {
+	_, err := fmt.Fprintf(n.W, "%s: %s\n", notification.Title, notification.Body)
+	return err
+}
+
+// CommandNotifier runs a program with the title and body appended to its
+// arguments, which suits desktop tools such as notify-send.
+type CommandNotifier struct {
+	Name string
+	Args []string
+}
+
+func (n *CommandNotifier) Notify(ctx context.Context, notification Notification) error {
+	args := append(append([]string{}, n.Args...), notification.Title, notification.Body)
+	output, err := exec.CommandContext(ctx, n.Name, args...).CombinedOutput()
+	if message := strings.TrimSpace(string(output)); err != nil && message != "" {
+		return fmt.Errorf("%s failed: %w: %s", n.Name, err, message)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", n.Name, err)
+	}
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to an endpoint on this machine.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns a notifier for rawURL, which must be an http or
+// https URL on a loopback address.
+func NewWebhookNotifier(rawURL string) (*WebhookNotifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL: %q", rawURL)
+	}
+	if ip := net.ParseIP(u.Hostname()); u.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("invalid webhook URL: %s is not a local address", u.Host)
+	}
+	return &WebhookNotifier{URL: rawURL, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
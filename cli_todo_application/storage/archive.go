@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	archiveBucketName = "archive"
+	openBucketName    = "open"
+)
+
+// forEachArchived calls fn with every archived task.
+func forEachArchived(tx *bolt.Tx, fn func(task Task) error) error {
+	archive := tx.Bucket([]byte(archiveBucketName))
+	if archive == nil {
+		return nil
+	}
+	return archive.ForEach(func(k, v []byte) error {
+		var task Task
+		if err := task.UnmarshalBinary(v); err != nil {
+			return fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+		return fn(task)
+	})
+}
+
+// archiveTask moves a task from the tasks bucket to the archive.
+func archiveTask(tx *bolt.Tx, id int) error {
+	task, err := readTask(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := removeTask(tx, id); err != nil {
+		return err
+	}
+
+	task.Archived = true
+	data, err := task.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	return tx.Bucket([]byte(archiveBucketName)).Put(itob(id), data)
+}
+
+// unarchiveTask drops the archived copy of a task that is being written back
+// to the tasks bucket, for example when an archive is undone.
+func unarchiveTask(tx *bolt.Tx, id int) error {
+	archive := tx.Bucket([]byte(archiveBucketName))
+	if archive == nil || archive.Get(itob(id)) == nil {
+		return nil
+	}
+	return archive.Delete(itob(id))
+}
+
+// archive moves a task to the archive and journals it so that undo brings it back.
+func (m *mutation) archive(id int) (*Task, error) {
+	before, err := readTask(m.tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := archiveTask(m.tx, id); err != nil {
+		return nil, err
+	}
+
+	m.record(id, before, nil)
+	m.changes[len(m.changes)-1].Archived = true
+	return before, nil
+}
+
+// GetArchivedTasks returns every archived task, in ID order.
+func (s *TaskStore) GetArchivedTasks() ([]Task, error) {
+	var tasks []Task
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachArchived(tx, func(task Task) error {
+			tasks = append(tasks, task)
+			return nil
+		})
+	})
+
+	return tasks, err
+}
+
+// ArchiveTasks moves tasks completed before cutoff out of the tasks bucket so
+// that day-to-day commands no longer read them. Archived tasks still appear in
+// completed history, statistics, reports and search. With dryRun the tasks
+// are returned without being archived.
+func (s *TaskStore) ArchiveTasks(cutoff time.Time, dryRun bool) ([]Task, error) {
+	var archived []Task
+
+	archive := func(m *mutation) error {
+		all, err := m.all()
+		if err != nil {
+			return err
+		}
+		for _, task := range all {
+			if !task.CompletedBetween(time.Time{}, cutoff) {
+				continue
+			}
+			archived = append(archived, task)
+			if dryRun {
+				continue
+			}
+			if _, err := m.archive(task.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if dryRun {
+		err := s.db.View(func(tx *bolt.Tx) error {
+			m, err := newMutation(tx)
+			if err != nil {
+				return err
+			}
+			return archive(m)
+		})
+		return archived, err
+	}
+	return archived, s.update("archive", archive)
+}
+
+// PurgeArchive permanently deletes archived tasks completed before cutoff, or
+// every archived task when cutoff is zero. Purging cannot be undone, so the
+// journal is trimmed up to the last entry that mentions a purged task. With
+// dryRun the tasks are returned without being deleted.
+func (s *TaskStore) PurgeArchive(cutoff time.Time, dryRun bool) ([]Task, error) {
+	var purged []Task
+
+	purge := func(tx *bolt.Tx) error {
+		err := forEachArchived(tx, func(task Task) error {
+			if cutoff.IsZero() || task.CompletedBetween(time.Time{}, cutoff) {
+				purged = append(purged, task)
+			}
+			return nil
+		})
+		if err != nil || dryRun || len(purged) == 0 {
+			return err
+		}
+
+		ids := make(map[int]bool, len(purged))
+		archive := tx.Bucket([]byte(archiveBucketName))
+		for _, task := range purged {
+			ids[task.ID] = true
+			if err := archive.Delete(itob(task.ID)); err != nil {
+				return err
+			}
+			if err := forgetSyncState(tx, task.UID); err != nil {
+				return err
+			}
+		}
+		return trimJournalMentioning(tx, ids)
+	}
+
+	var err error
+	if dryRun {
+		err = s.db.View(purge)
+	} else {
+		err = s.db.Update(purge)
+	}
+
+	sort.Slice(purged, func(i, j int) bool { return purged[i].ID < purged[j].ID })
+	return purged, err
+}
+
+// trimJournalMentioning deletes the journal entries up to and including the
+// newest one that changes any of the given tasks. Older entries are removed
+// too, since undo must never skip over an entry.
+func trimJournalMentioning(tx *bolt.Tx, ids map[int]bool) error {
+	journal := tx.Bucket([]byte(journalBucketName))
+	if journal == nil {
+		return nil
+	}
+
+	var last []byte
+	c := journal.Cursor()
+	for k, v := c.Last(); k != nil && last == nil; k, v = c.Prev() {
+		var entry JournalEntry
+		if err := entry.UnmarshalBinary(v); err != nil {
+			return fmt.Errorf("failed to unmarshal journal entry: %w", err)
+		}
+		for _, change := range entry.Changes {
+			if ids[change.ID] {
+				last = k
+				break
+			}
+		}
+	}
+	if last == nil {
+		return nil
+	}
+
+	var stale [][]byte
+	for k, _ := c.First(); k != nil && btoi(k) <= btoi(last); k, _ = c.Next() {
+		stale = append(stale, k)
+	}
+	for _, k := range stale {
+		if err := journal.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSelection is returned when a batch selection cannot be resolved,
+// for example because of a malformed range or an out-of-range position.
+var ErrInvalidSelection = errors.New("invalid task selection")
+
+// Selection picks the tasks a batch operation works on. Refs are task IDs,
+// ID ranges such as "5-7" and description prefixes; with ByPosition they are
+// positions and position ranges in Project's incomplete list instead. When a
+// Filter is given only the selected tasks matching it are kept, and without
+// Refs it selects the matching incomplete tasks in Project. An empty Project
+// means the default project.
+type Selection struct {
+	Refs       []string    `json:"refs,omitempty"`
+	ByPosition bool        `json:"by_position,omitempty"`
+	Project    string      `json:"project,omitempty"`
+	Filter     *TaskFilter `json:"filter,omitempty"`
+}
+
+// CompleteTasks completes every selected task in a single transaction, so
+// either all of them are completed or none is. The selection is resolved in
+// the same transaction, before anything changes. It returns every task that
+// was completed, including subtasks completed because of force, in ID order.
+func (s *TaskStore) CompleteTasks(sel Selection, force bool) ([]Task, error) {
+	var completed []Task
+
+	err := s.update("complete", func(m *mutation) error {
+		targets, err := m.selectTasks(sel)
+		if err != nil {
+			return err
+		}
+
+		// Complete subtasks before their parents, so that a parent selected
+		// together with its open subtasks does not need force.
+		all, err := m.all()
+		if err != nil {
+			return err
+		}
+		depths := taskDepths(all)
+		sort.SliceStable(targets, func(i, j int) bool { return depths[targets[i].ID] > depths[targets[j].ID] })
+
+		now := time.Now()
+		for _, target := range targets {
+			if _, err := m.complete(target.ID, force, now); err != nil {
+				return err
+			}
+		}
+
+		for _, change := range m.changes {
+			if change.After != nil && change.After.Completed && change.Before != nil && !change.Before.Completed {
+				completed = append(completed, *change.After)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(completed, func(i, j int) bool { return completed[i].ID < completed[j].ID })
+	return completed, err
+}
+
+// DeleteTasks deletes every selected task in a single transaction. The
+// selection is resolved before anything is deleted, so positions refer to
+// the list as it was when the command started. It returns the deleted tasks
+// in ID order.
+func (s *TaskStore) DeleteTasks(sel Selection) ([]Task, error) {
+	var deleted []Task
+
+	err := s.update("delete", func(m *mutation) error {
+		targets, err := m.selectTasks(sel)
+		if err != nil {
+			return err
+		}
+
+		for _, target := range targets {
+			task, err := m.deleteTask(target.ID)
+			if err != nil {
+				return err
+			}
+			deleted = append(deleted, *task)
+		}
+		return nil
+	})
+
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].ID < deleted[j].ID })
+	return deleted, err
+}
+
+// selectTasks resolves sel against the current state of the transaction.
+// Each task is returned once, in the order it was first referenced.
+func (m *mutation) selectTasks(sel Selection) ([]Task, error) {
+	var open []Task
+	err := forEachOpen(m.tx, func(task Task) error {
+		open = append(open, task)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	project := sel.Project
+	if project == "" {
+		project = readDefaultProject(m.tx)
+	}
+	var projectTasks []Task
+	for _, task := range open {
+		if task.ProjectName() == project {
+			projectTasks = append(projectTasks, task)
+		}
+	}
+
+	var selected []Task
+	if len(sel.Refs) == 0 {
+		selected = projectTasks
+	}
+	for _, ref := range sel.Refs {
+		tasks, err := m.resolveRef(ref, sel.ByPosition, open, projectTasks)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, tasks...)
+	}
+
+	seen := make(map[int]bool, len(selected))
+	var tasks []Task
+	for _, task := range selected {
+		if seen[task.ID] || sel.Filter != nil && !sel.Filter.Match(task) {
+			continue
+		}
+		seen[task.ID] = true
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// resolveRef resolves a single reference of a selection. A single ID may
+// name any task, while an ID range only covers incomplete tasks, since the
+// IDs of deleted tasks leave gaps.
+func (m *mutation) resolveRef(ref string, byPosition bool, open, projectTasks []Task) ([]Task, error) {
+	ref = strings.TrimSpace(ref)
+	lo, hi, isRange, err := parseRange(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if byPosition {
+		if !isRange {
+			position, err := strconv.Atoi(ref)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid task number: %s", ErrInvalidSelection, ref)
+			}
+			lo, hi = position, position
+		}
+		if lo < 1 || hi > len(projectTasks) {
+			return nil, fmt.Errorf("%w: invalid task number: %s (must be between 1 and %d)", ErrInvalidSelection, ref, len(projectTasks))
+		}
+		return slices.Clone(projectTasks[lo-1 : hi]), nil
+	}
+
+	if isRange {
+		var tasks []Task
+		for _, task := range open {
+			if task.ID >= lo && task.ID <= hi {
+				tasks = append(tasks, task)
+			}
+		}
+		if len(tasks) == 0 {
+			return nil, fmt.Errorf("%w: no incomplete tasks in range %s", ErrTaskNotFound, ref)
+		}
+		return tasks, nil
+	}
+
+	if id, err := strconv.Atoi(ref); err == nil {
+		task, err := m.get(id)
+		if err != nil {
+			return nil, err
+		}
+		return []Task{*task}, nil
+	}
+
+	task, err := matchPrefix(open, ref)
+	if err != nil {
+		return nil, err
+	}
+	return []Task{*task}, nil
+}
+
+// parseRange parses references of the form "5-7". It reports isRange false
+// for anything that does not look like a range, such as a description.
+func parseRange(ref string) (lo, hi int, isRange bool, err error) {
+	from, to, found := strings.Cut(ref, "-")
+	if !found {
+		return 0, 0, false, nil
+	}
+	lo, errLo := strconv.Atoi(from)
+	hi, errHi := strconv.Atoi(to)
+	if errLo != nil || errHi != nil {
+		return 0, 0, false, nil
+	}
+	if lo < 1 || hi < lo {
+		return 0, 0, false, fmt.Errorf("%w: invalid range: %s", ErrInvalidSelection, ref)
+	}
+	return lo, hi, true, nil
+}
+
+// taskDepths returns how many ancestors each task has.
+func taskDepths(tasks []Task) map[int]int {
+	parents := make(map[int]int, len(tasks))
+	for _, task := range tasks {
+		parents[task.ID] = task.ParentID
+	}
+
+	depths := make(map[int]int, len(tasks))
+	for _, task := range tasks {
+		depth := 0
+		for parent := task.ParentID; parent != 0 && depth < len(tasks); parent = parents[parent] {
+			depth++
+		}
+		depths[task.ID] = depth
+	}
+	return depths
+}
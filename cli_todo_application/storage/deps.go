@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+var (
+	ErrOpenSubtasks   = errors.New("task has open subtasks")
+	ErrDependencyLoop = errors.New("dependency would create a cycle")
+)
+
+// TreeNode is a task with its depth in the parent/child hierarchy.
+type TreeNode struct {
+	Task  Task
+	Depth int
+}
+
+// TaskTree orders tasks so that every child follows its parent, keeping the
+// existing order among siblings. Tasks whose parent is not in the slice are
+// treated as roots.
+func TaskTree(tasks []Task) []TreeNode {
+	present := make(map[int]bool, len(tasks))
+	for _, task := range tasks {
+		present[task.ID] = true
+	}
+
+	children := make(map[int][]Task)
+	var roots []Task
+	for _, task := range tasks {
+		if task.ParentID != 0 && present[task.ParentID] && task.ParentID != task.ID {
+			children[task.ParentID] = append(children[task.ParentID], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	nodes := make([]TreeNode, 0, len(tasks))
+	visited := make(map[int]bool, len(tasks))
+	var walk func(task Task, depth int)
+	walk = func(task Task, depth int) {
+		if visited[task.ID] {
+			return
+		}
+		visited[task.ID] = true
+		nodes = append(nodes, TreeNode{Task: task, Depth: depth})
+		for _, child := range children[task.ID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	return nodes
+}
+
+// OpenBlockers returns the IDs in t.BlockedBy that refer to tasks in open,
+// the set of incomplete task IDs.
+func (t Task) OpenBlockers(open map[int]bool) []int {
+	var blockers []int
+	for _, id := range t.BlockedBy {
+		if open[id] {
+			blockers = append(blockers, id)
+		}
+	}
+	return blockers
+}
+
+// OpenTaskIDs returns the set of IDs of the given tasks that are incomplete.
+func OpenTaskIDs(tasks []Task) map[int]bool {
+	open := make(map[int]bool, len(tasks))
+	for _, task := range tasks {
+		if !task.Completed {
+			open[task.ID] = true
+		}
+	}
+	return open
+}
+
+// NextTasks suggests up to limit incomplete tasks in project to work on next:
+// tasks that are not blocked by open tasks and have no open subtasks, with
+// overdue tasks first, then by priority, due date and age. A limit of zero
+// returns every candidate.
+func (s *TaskStore) NextTasks(project string, limit int) ([]Task, error) {
+	open, err := s.GetIncompleteTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incomplete tasks: %w", err)
+	}
+
+	openIDs := OpenTaskIDs(open)
+	hasOpenChildren := make(map[int]bool)
+	for _, task := range open {
+		if task.ParentID != 0 {
+			hasOpenChildren[task.ParentID] = true
+		}
+	}
+
+	var candidates []Task
+	for _, task := range open {
+		if task.ProjectName() != project || hasOpenChildren[task.ID] || len(task.OpenBlockers(openIDs)) > 0 {
+			continue
+		}
+		candidates = append(candidates, task)
+	}
+
+	now := time.Now()
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.IsOverdue(now) != b.IsOverdue(now) {
+			return a.IsOverdue(now)
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if (a.Due == nil) != (b.Due == nil) {
+			return a.Due != nil
+		}
+		if a.Due != nil && !a.Due.Equal(*b.Due) {
+			return a.Due.Before(*b.Due)
+		}
+		return a.ID < b.ID
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// AddDependency records that the task with the given ID is blocked by blockerID.
+func (s *TaskStore) AddDependency(id, blockerID int) (*Task, error) {
+	var task *Task
+
+	err := s.update("block", func(m *mutation) error {
+		var err error
+		if task, err = m.get(id); err != nil {
+			return err
+		}
+		if _, err := m.get(blockerID); err != nil {
+			return err
+		}
+		if slices.Contains(task.BlockedBy, blockerID) {
+			return fmt.Errorf("task %d is already blocked by task %d", id, blockerID)
+		}
+
+		all, err := m.all()
+		if err != nil {
+			return err
+		}
+		if dependsOn(all, blockerID, id) {
+			return fmt.Errorf("%w: task %d already depends on task %d", ErrDependencyLoop, blockerID, id)
+		}
+
+		task.BlockedBy = append(task.BlockedBy, blockerID)
+		return m.put(task)
+	})
+
+	return task, err
+}
+
+// RemoveDependency removes blockerID from the blockers of the task with the given ID.
+func (s *TaskStore) RemoveDependency(id, blockerID int) (*Task, error) {
+	var task *Task
+
+	err := s.update("unblock", func(m *mutation) error {
+		var err error
+		if task, err = m.get(id); err != nil {
+			return err
+		}
+
+		i := slices.Index(task.BlockedBy, blockerID)
+		if i < 0 {
+			return fmt.Errorf("task %d is not blocked by task %d", id, blockerID)
+		}
+
+		task.BlockedBy = slices.Delete(task.BlockedBy, i, i+1)
+		return m.put(task)
+	})
+
+	return task, err
+}
+
+// dependsOn reports whether from transitively depends on target. A task
+// depends on the tasks blocking it and, since a parent cannot be completed
+// before its subtasks, a parent depends on its children.
+func dependsOn(tasks []Task, from, target int) bool {
+	edges := make(map[int][]int, len(tasks))
+	for _, task := range tasks {
+		edges[task.ID] = append(edges[task.ID], task.BlockedBy...)
+		if task.ParentID != 0 {
+			edges[task.ParentID] = append(edges[task.ParentID], task.ID)
+		}
+	}
+
+	visited := make(map[int]bool)
+	stack := []int{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == target {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		stack = append(stack, edges[id]...)
+	}
+	return false
+}
+
+// all returns every task visible to the mutation's transaction.
+func (m *mutation) all() ([]Task, error) {
+	var tasks []Task
+	err := m.tasks.ForEach(func(k, v []byte) error {
+		var task Task
+		if err := task.UnmarshalBinary(v); err != nil {
+			return fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+		tasks = append(tasks, task)
+		return nil
+	})
+	return tasks, err
+}
+
+// completeSubtree marks every open descendant of id as complete.
+func (m *mutation) completeSubtree(all []Task, id int, now time.Time) error {
+	for _, task := range all {
+		if task.ParentID != id || task.Completed {
+			continue
+		}
+		if err := m.completeSubtree(all, task.ID, now); err != nil {
+			return err
+		}
+
+		task.Completed = true
+		task.CompletedAt = &now
+		task.stopTimer(now)
+		if task.Recurrence != nil {
+			next, err := m.addNextOccurrence(&task, now)
+			if err != nil {
+				return err
+			}
+			task.NextID = next.ID
+		}
+		if err := m.put(&task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detach removes references to a deleted task: its children move up to its
+// parent and it is dropped from every blocked-by list.
+func (m *mutation) detach(all []Task, deleted *Task) error {
+	for _, task := range all {
+		changed := false
+		if task.ParentID == deleted.ID {
+			task.ParentID = deleted.ParentID
+			changed = true
+		}
+		if i := slices.Index(task.BlockedBy, deleted.ID); i >= 0 {
+			task.BlockedBy = slices.Delete(task.BlockedBy, i, i+1)
+			changed = true
+		}
+		if changed {
+			if err := m.put(&task); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
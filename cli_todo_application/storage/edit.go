@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrTaskChanged is returned by EditTask when the task was updated after the
+// edit was prepared.
+var ErrTaskChanged = errors.New("task was changed in the meantime")
+
+// TaskEdit describes changes to a task's details. Zero fields leave the task
+// as it is.
+type TaskEdit struct {
+	Description string     `json:"description,omitempty"`
+	Due         *time.Time `json:"due,omitempty"`
+	ClearDue    bool       `json:"clear_due,omitempty"`
+	Priority    *Priority  `json:"priority,omitempty"`
+	AddTags     []string   `json:"add_tags,omitempty"`
+	RemoveTags  []string   `json:"remove_tags,omitempty"`
+	// UnchangedSince makes the edit fail with ErrTaskChanged unless the
+	// task was last updated at exactly this time, so that an edit based on
+	// an earlier read does not overwrite later changes.
+	UnchangedSince *time.Time `json:"unchanged_since,omitempty"`
+}
+
+// EditTask applies edit to the task with the given ID as a single undoable
+// change.
+func (s *TaskStore) EditTask(id int, edit TaskEdit) (*Task, error) {
+	return s.UpdateTask(id, func(task *Task) error {
+		if edit.UnchangedSince != nil && !task.UpdatedAt.Equal(*edit.UnchangedSince) {
+			return fmt.Errorf("%w: task %d", ErrTaskChanged, task.ID)
+		}
+		if edit.Description != "" {
+			task.Description = edit.Description
+		}
+		if edit.Due != nil {
+			task.Due = edit.Due
+		}
+		if edit.ClearDue {
+			task.Due = nil
+		}
+		if edit.Priority != nil {
+			task.Priority = *edit.Priority
+		}
+		task.Tags = append(task.Tags, edit.AddTags...)
+		for _, tag := range NormalizeTags(edit.RemoveTags) {
+			if i := slices.Index(task.Tags, tag); i >= 0 {
+				task.Tags = slices.Delete(task.Tags, i, i+1)
+			}
+		}
+		return nil
+	})
+}
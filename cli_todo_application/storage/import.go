@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ImportResult lists the tasks an import added and the ones it skipped as
+// duplicates of tasks already in the store or earlier in the same import.
+type ImportResult struct {
+	Added   []Task `json:"added"`
+	Skipped []Task `json:"skipped"`
+}
+
+// GetAllTasks returns every task in the store, completed or not, in ID order.
+func (s *TaskStore) GetAllTasks() ([]Task, error) {
+	var tasks []Task
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var task Task
+			if err := task.UnmarshalBinary(v); err != nil {
+				return fmt.Errorf("failed to unmarshal task: %w", err)
+			}
+			tasks = append(tasks, task)
+			return nil
+		})
+	})
+
+	return tasks, err
+}
+
+// ImportTasks adds tasks that are not already present, archived ones included.
+// Two tasks are duplicates when their descriptions match ignoring case and
+// surrounding whitespace and they have the same completion state. Imported
+// tasks get fresh IDs, and their subtask and blocker references follow them.
+// Completed tasks marked as archived go straight to the archive. With dryRun
+// the result is computed without changing the store.
+func (s *TaskStore) ImportTasks(tasks []Task, dryRun bool) (*ImportResult, error) {
+	existing, err := s.GetAllTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	archived, err := s.GetArchivedTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archived tasks: %w", err)
+	}
+	existing = append(existing, archived...)
+
+	seen := make(map[string]bool, len(existing))
+	for _, task := range existing {
+		seen[importKey(task)] = true
+	}
+
+	result := &ImportResult{}
+	for _, task := range tasks {
+		key := importKey(task)
+		if seen[key] {
+			result.Skipped = append(result.Skipped, task)
+			continue
+		}
+		seen[key] = true
+		result.Added = append(result.Added, task)
+	}
+
+	if dryRun || len(result.Added) == 0 {
+		return result, nil
+	}
+
+	err = s.update("import", func(m *mutation) error {
+		// IDs in the file belong to the database it came from. References
+		// between imported tasks are renumbered with them; references to
+		// anything else would point at unrelated local tasks and are dropped.
+		newIDs := make(map[int]int, len(result.Added))
+		for i := range result.Added {
+			id, err := m.nextID()
+			if err != nil {
+				return err
+			}
+			if old := result.Added[i].ID; old != 0 && newIDs[old] == 0 {
+				newIDs[old] = id
+			}
+			result.Added[i].ID = id
+		}
+
+		now := time.Now()
+		for i := range result.Added {
+			task := &result.Added[i]
+			task.UID = ""
+			task.Tags = NormalizeTags(task.Tags)
+			task.NextID = 0
+			task.SeriesID = 0
+			if task.Recurrence != nil {
+				task.SeriesID = task.ID
+			}
+			if task.CreatedAt.IsZero() {
+				task.CreatedAt = now
+			}
+			if task.Completed && task.CompletedAt == nil {
+				task.CompletedAt = &now
+			}
+
+			task.ParentID = newIDs[task.ParentID]
+			if task.ParentID == task.ID {
+				task.ParentID = 0
+			}
+			var blockedBy []int
+			for _, old := range task.BlockedBy {
+				if id := newIDs[old]; id != 0 && id != task.ID && !slices.Contains(blockedBy, id) {
+					blockedBy = append(blockedBy, id)
+				}
+			}
+			task.BlockedBy = blockedBy
+			task.TimeEntries = importTimeEntries(task.TimeEntries)
+
+			archive := task.Archived && task.Completed
+			if err := m.put(task); err != nil {
+				return err
+			}
+			if archive {
+				if _, err := m.archive(task.ID); err != nil {
+					return err
+				}
+				task.Archived = true
+			}
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// importTimeEntries keeps the finished, well-formed entries. A timer still
+// running in the database the tasks came from is not running here.
+func importTimeEntries(entries []TimeEntry) []TimeEntry {
+	var kept []TimeEntry
+	for _, entry := range entries {
+		if entry.Start.IsZero() || entry.End == nil || entry.End.Before(entry.Start) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+func importKey(task Task) string {
+	return fmt.Sprintf("%t|%s", task.Completed, strings.ToLower(strings.TrimSpace(task.Description)))
+}
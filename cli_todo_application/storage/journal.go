@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const journalBucketName = "journal"
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// TaskChange records the state of a single task before and after a mutation.
+// A nil Before means the task was created; a nil After means it was deleted,
+// or moved to the archive when Archived is set.
+type TaskChange struct {
+	ID       int   `json:"id"`
+	Before   *Task `json:"before,omitempty"`
+	After    *Task `json:"after,omitempty"`
+	Archived bool  `json:"archived,omitempty"`
+}
+
+// JournalEntry is one mutation made through TaskStore. Entries are kept in the
+// journal bucket in the order they were made; undone entries stay at the tail
+// until they are redone or discarded by a new mutation.
+type JournalEntry struct {
+	ID      int          `json:"id"`
+	Op      string       `json:"op"`
+	Time    time.Time    `json:"time"`
+	Changes []TaskChange `json:"changes"`
+	Undone  bool         `json:"undone,omitempty"`
+}
+
+func (e JournalEntry) MarshalBinary() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (e *JournalEntry) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, e)
+}
+
+// Summary describes the entry in a single line, e.g. `complete "Fix login"`.
+func (e JournalEntry) Summary() string {
+	if len(e.Changes) == 0 {
+		return e.Op
+	}
+
+	change := e.Changes[0]
+	task := change.After
+	if task == nil {
+		task = change.Before
+	}
+
+	summary := fmt.Sprintf("%s %q", e.Op, task.Description)
+	if len(e.Changes) > 1 {
+		summary += fmt.Sprintf(" and %d more", len(e.Changes)-1)
+	}
+	return summary
+}
+
+// mutation collects every task change made inside one bbolt write transaction
+// so that it can be journaled as a single undoable operation.
+type mutation struct {
+	tx      *bolt.Tx
+	tasks   *bolt.Bucket
+	changes []TaskChange
+}
+
+func newMutation(tx *bolt.Tx) (*mutation, error) {
+	bucket := tx.Bucket([]byte(bucketName))
+	if bucket == nil {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+	return &mutation{tx: tx, tasks: bucket}, nil
+}
+
+func (m *mutation) nextID() (int, error) {
+	id, err := m.tasks.NextSequence()
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return int(id), nil
+}
+
+func (m *mutation) get(id int) (*Task, error) {
+	return readTask(m.tx, id)
+}
+
+// put stores task, stamping it as updated now.
+func (m *mutation) put(task *Task) error {
+	return m.putAt(task, time.Now())
+}
+
+// putAt stores task with the given update time.
+func (m *mutation) putAt(task *Task, updated time.Time) error {
+	task.UpdatedAt = updated
+
+	before, err := readTask(m.tx, task.ID)
+	if err != nil && !errors.Is(err, ErrTaskNotFound) {
+		return err
+	}
+	if err := writeTask(m.tx, task); err != nil {
+		return err
+	}
+
+	after := *task
+	m.record(task.ID, before, &after)
+	return nil
+}
+
+func (m *mutation) delete(id int) (*Task, error) {
+	before, err := readTask(m.tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := removeTask(m.tx, id); err != nil {
+		return nil, err
+	}
+
+	m.record(id, before, nil)
+	return before, nil
+}
+
+// record merges repeated changes to the same task so that the journal keeps
+// the state before the first change and after the last one.
+func (m *mutation) record(id int, before, after *Task) {
+	for i := range m.changes {
+		if m.changes[i].ID == id {
+			m.changes[i].After = after
+			return
+		}
+	}
+	m.changes = append(m.changes, TaskChange{ID: id, Before: before, After: after})
+}
+
+// commit journals the collected changes. Any undone entries are discarded,
+// since they can no longer be redone on top of the new state.
+func (m *mutation) commit(op string) error {
+	if len(m.changes) == 0 {
+		return nil
+	}
+
+	journal := m.tx.Bucket([]byte(journalBucketName))
+	if journal == nil {
+		return fmt.Errorf("bucket %s not found", journalBucketName)
+	}
+
+	if err := discardUndone(journal); err != nil {
+		return err
+	}
+
+	id, err := journal.NextSequence()
+	if err != nil {
+		return fmt.Errorf("failed to generate journal ID: %w", err)
+	}
+
+	entry := JournalEntry{ID: int(id), Op: op, Time: time.Now(), Changes: m.changes}
+	return putEntry(journal, &entry)
+}
+
+// update runs fn in a write transaction and journals its changes under op.
+func (s *TaskStore) update(op string, fn func(m *mutation) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		m, err := newMutation(tx)
+		if err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+		return m.commit(op)
+	})
+}
+
+func putEntry(journal *bolt.Bucket, entry *JournalEntry) error {
+	data, err := entry.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+	return journal.Put(itob(entry.ID), data)
+}
+
+func discardUndone(journal *bolt.Bucket) error {
+	var stale [][]byte
+
+	c := journal.Cursor()
+	for k, v := c.Last(); k != nil; k, v = c.Prev() {
+		var entry JournalEntry
+		if err := entry.UnmarshalBinary(v); err != nil {
+			return fmt.Errorf("failed to unmarshal journal entry: %w", err)
+		}
+		if !entry.Undone {
+			break
+		}
+		stale = append(stale, k)
+	}
+
+	for _, k := range stale {
+		if err := journal.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Undo reverts the most recent mutation that has not been undone yet.
+func (s *TaskStore) Undo() (*JournalEntry, error) {
+	var entry *JournalEntry
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		journal := tx.Bucket([]byte(journalBucketName))
+		if journal == nil {
+			return fmt.Errorf("bucket %s not found", journalBucketName)
+		}
+
+		c := journal.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var candidate JournalEntry
+			if err := candidate.UnmarshalBinary(v); err != nil {
+				return fmt.Errorf("failed to unmarshal journal entry: %w", err)
+			}
+			if !candidate.Undone {
+				entry = &candidate
+				break
+			}
+		}
+		if entry == nil {
+			return ErrNothingToUndo
+		}
+
+		for i := len(entry.Changes) - 1; i >= 0; i-- {
+			change := entry.Changes[i]
+			if err := applyState(tx, change.ID, change.Before); err != nil {
+				return err
+			}
+		}
+
+		entry.Undone = true
+		return putEntry(journal, entry)
+	})
+
+	return entry, err
+}
+
+// Redo reapplies the earliest undone mutation.
+func (s *TaskStore) Redo() (*JournalEntry, error) {
+	var entry *JournalEntry
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		journal := tx.Bucket([]byte(journalBucketName))
+		if journal == nil {
+			return fmt.Errorf("bucket %s not found", journalBucketName)
+		}
+
+		c := journal.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var candidate JournalEntry
+			if err := candidate.UnmarshalBinary(v); err != nil {
+				return fmt.Errorf("failed to unmarshal journal entry: %w", err)
+			}
+			if !candidate.Undone {
+				break
+			}
+			entry = &candidate
+		}
+		if entry == nil {
+			return ErrNothingToRedo
+		}
+
+		for _, change := range entry.Changes {
+			if change.Archived {
+				if err := archiveTask(tx, change.ID); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := applyState(tx, change.ID, change.After); err != nil {
+				return err
+			}
+		}
+
+		entry.Undone = false
+		return putEntry(journal, entry)
+	})
+
+	return entry, err
+}
+
+// GetJournal returns up to limit of the most recent journal entries, newest
+// first. A limit of zero returns every entry.
+func (s *TaskStore) GetJournal(limit int) ([]JournalEntry, error) {
+	var entries []JournalEntry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		journal := tx.Bucket([]byte(journalBucketName))
+		if journal == nil {
+			return nil
+		}
+
+		c := journal.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if limit > 0 && len(entries) >= limit {
+				break
+			}
+
+			var entry JournalEntry
+			if err := entry.UnmarshalBinary(v); err != nil {
+				return fmt.Errorf("failed to unmarshal journal entry: %w", err)
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+
+	return entries, err
+}
+
+// TrimJournal deletes all but the newest keep entries and returns how many
+// entries were removed. Trimmed mutations can no longer be undone.
+func (s *TaskStore) TrimJournal(keep int) (int, error) {
+	if keep < 0 {
+		return 0, fmt.Errorf("invalid journal size: %d", keep)
+	}
+
+	removed := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		journal := tx.Bucket([]byte(journalBucketName))
+		if journal == nil {
+			return fmt.Errorf("bucket %s not found", journalBucketName)
+		}
+
+		var keys [][]byte
+		c := journal.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			keys = append(keys, k)
+		}
+		if len(keys) <= keep {
+			return nil
+		}
+
+		keys = keys[:len(keys)-keep]
+		for _, k := range keys {
+			if err := journal.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+
+	return removed, err
+}
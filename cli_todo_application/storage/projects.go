@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	projectsBucketName = "projects"
+	configBucketName   = "config"
+
+	// InboxProject holds tasks created before projects existed and is the
+	// default project until another one is configured.
+	InboxProject = "inbox"
+
+	defaultProjectKey = "default_project"
+)
+
+// ProjectInfo summarizes one project for "task projects".
+type ProjectInfo struct {
+	Name    string `json:"name"`
+	Open    int    `json:"open"`
+	Total   int    `json:"total"`
+	Default bool   `json:"default"`
+}
+
+// NormalizeProject lowercases and trims a project name and rejects names that
+// cannot round-trip through the todo.txt +project syntax.
+func NormalizeProject(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "+")))
+	if name == "" {
+		return "", fmt.Errorf("project name cannot be empty")
+	}
+	if strings.ContainsAny(name, " \t\n") {
+		return "", fmt.Errorf("invalid project name: %q (must not contain whitespace)", name)
+	}
+	return name, nil
+}
+
+// ProjectName returns the project the task belongs to.
+func (t Task) ProjectName() string {
+	if t.Project == "" {
+		return InboxProject
+	}
+	return t.Project
+}
+
+// initIndexes creates the project and open-task indexes, populating them from
+// the tasks bucket when one of them is new so that existing databases keep
+// working.
+func initIndexes(tx *bolt.Tx) error {
+	created := false
+	for _, name := range []string{projectsBucketName, openBucketName} {
+		if tx.Bucket([]byte(name)) != nil {
+			continue
+		}
+		if _, err := tx.CreateBucket([]byte(name)); err != nil {
+			return err
+		}
+		created = true
+	}
+	if !created {
+		return nil
+	}
+
+	tasks := tx.Bucket([]byte(bucketName))
+	return tasks.ForEach(func(k, v []byte) error {
+		var task Task
+		if err := task.UnmarshalBinary(v); err != nil {
+			return fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+		return reindexTask(tx, nil, &task)
+	})
+}
+
+// reindexTask moves a task's index entries from its old state to its new one.
+// Either state may be nil for a created or deleted task. The project index
+// holds every task; the open index only incomplete ones.
+func reindexTask(tx *bolt.Tx, old, updated *Task) error {
+	projects := tx.Bucket([]byte(projectsBucketName))
+	if projects == nil {
+		return fmt.Errorf("bucket %s not found", projectsBucketName)
+	}
+
+	if old != nil && (updated == nil || old.ProjectName() != updated.ProjectName()) {
+		if project := projects.Bucket([]byte(old.ProjectName())); project != nil {
+			if err := project.Delete(itob(old.ID)); err != nil {
+				return err
+			}
+			if isEmptyBucket(project) {
+				if err := projects.DeleteBucket([]byte(old.ProjectName())); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if updated != nil {
+		project, err := projects.CreateBucketIfNotExists([]byte(updated.ProjectName()))
+		if err != nil {
+			return err
+		}
+		if err := project.Put(itob(updated.ID), []byte{}); err != nil {
+			return err
+		}
+	}
+
+	open := tx.Bucket([]byte(openBucketName))
+	if open == nil {
+		return fmt.Errorf("bucket %s not found", openBucketName)
+	}
+	if updated != nil && !updated.Completed {
+		return open.Put(itob(updated.ID), []byte{})
+	}
+	if old != nil && !old.Completed {
+		return open.Delete(itob(old.ID))
+	}
+	return nil
+}
+
+// forEachOpen calls fn with every incomplete task, in ID order.
+func forEachOpen(tx *bolt.Tx, fn func(task Task) error) error {
+	open := tx.Bucket([]byte(openBucketName))
+	if open == nil {
+		return nil
+	}
+	return open.ForEach(func(k, _ []byte) error {
+		task, err := readTask(tx, btoi(k))
+		if err != nil {
+			return err
+		}
+		return fn(*task)
+	})
+}
+
+func isEmptyBucket(bucket *bolt.Bucket) bool {
+	k, _ := bucket.Cursor().First()
+	return k == nil
+}
+
+// GetProjectTasks returns the incomplete tasks in a project, in ID order.
+func (s *TaskStore) GetProjectTasks(project string) ([]Task, error) {
+	var tasks []Task
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachOpen(tx, func(task Task) error {
+			if task.ProjectName() == project {
+				tasks = append(tasks, task)
+			}
+			return nil
+		})
+	})
+
+	return tasks, err
+}
+
+// GetProjects lists every project that has tasks, plus the default project.
+func (s *TaskStore) GetProjects() ([]ProjectInfo, error) {
+	var infos []ProjectInfo
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		defaultProject := readDefaultProject(tx)
+		hasDefault := false
+
+		projects := tx.Bucket([]byte(projectsBucketName))
+		if projects != nil {
+			err := projects.ForEachBucket(func(name []byte) error {
+				info := ProjectInfo{Name: string(name), Default: string(name) == defaultProject}
+				hasDefault = hasDefault || info.Default
+
+				err := projects.Bucket(name).ForEach(func(k, _ []byte) error {
+					task, err := readTask(tx, btoi(k))
+					if err != nil {
+						return err
+					}
+					info.Total++
+					if !task.Completed {
+						info.Open++
+					}
+					return nil
+				})
+
+				infos = append(infos, info)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if !hasDefault {
+			infos = append(infos, ProjectInfo{Name: defaultProject, Default: true})
+		}
+		return nil
+	})
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, err
+}
+
+// MoveTask assigns the task with the given ID to another project.
+func (s *TaskStore) MoveTask(id int, project string) (*Task, error) {
+	project, err := NormalizeProject(project)
+	if err != nil {
+		return nil, err
+	}
+
+	var task *Task
+
+	err = s.update("move", func(m *mutation) error {
+		var err error
+		task, err = m.get(id)
+		if err != nil {
+			return err
+		}
+		if task.ProjectName() == project {
+			return fmt.Errorf("task %d is already in project %s", id, project)
+		}
+
+		task.Project = project
+		return m.put(task)
+	})
+
+	return task, err
+}
+
+// DefaultProject returns the project new tasks go into when none is given.
+func (s *TaskStore) DefaultProject() (string, error) {
+	var project string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		project = readDefaultProject(tx)
+		return nil
+	})
+
+	return project, err
+}
+
+func (s *TaskStore) SetDefaultProject(project string) error {
+	project, err := NormalizeProject(project)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		config := tx.Bucket([]byte(configBucketName))
+		if config == nil {
+			return fmt.Errorf("bucket %s not found", configBucketName)
+		}
+		return config.Put([]byte(defaultProjectKey), []byte(project))
+	})
+}
+
+func readDefaultProject(tx *bolt.Tx) string {
+	if config := tx.Bucket([]byte(configBucketName)); config != nil {
+		if project := config.Get([]byte(defaultProjectKey)); project != nil {
+			return string(project)
+		}
+	}
+	return InboxProject
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RecurrenceKind string
+
+const (
+	RecurDaily    RecurrenceKind = "daily"
+	RecurWeekdays RecurrenceKind = "weekdays"
+	RecurWeekly   RecurrenceKind = "weekly"
+	RecurMonthly  RecurrenceKind = "monthly"
+)
+
+// Recurrence describes when a repeating task comes due again. Weekly rules
+// list the days they fall on; monthly rules fall on DayOfMonth, clamped to
+// the last day of shorter months.
+type Recurrence struct {
+	Kind       RecurrenceKind `json:"kind"`
+	Weekdays   []time.Weekday `json:"weekdays,omitempty"`
+	DayOfMonth int            `json:"day_of_month,omitempty"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseRecurrence parses rules of the form "daily", "weekdays",
+// "weekly:mon,thu" and "monthly:15". A bare "weekly" or "monthly" repeats on
+// the weekday or day of month of start.
+func ParseRecurrence(rule string, start time.Time) (*Recurrence, error) {
+	kind, arg, _ := strings.Cut(strings.ToLower(strings.TrimSpace(rule)), ":")
+
+	switch RecurrenceKind(kind) {
+	case RecurDaily, RecurWeekdays:
+		if arg != "" {
+			return nil, fmt.Errorf("invalid recurrence: %q takes no arguments", kind)
+		}
+		return &Recurrence{Kind: RecurrenceKind(kind)}, nil
+
+	case RecurWeekly:
+		r := &Recurrence{Kind: RecurWeekly}
+		if arg == "" {
+			r.Weekdays = []time.Weekday{start.Weekday()}
+			return r, nil
+		}
+
+		seen := make(map[time.Weekday]bool)
+		for _, name := range strings.Split(arg, ",") {
+			name = strings.TrimSpace(name)
+			if len(name) > 3 {
+				name = name[:3]
+			}
+			day, ok := weekdayNames[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid recurrence: unknown weekday %q", name)
+			}
+			if !seen[day] {
+				seen[day] = true
+				r.Weekdays = append(r.Weekdays, day)
+			}
+		}
+		return r, nil
+
+	case RecurMonthly:
+		day := start.Day()
+		if arg != "" {
+			var err error
+			day, err = strconv.Atoi(arg)
+			if err != nil || day < 1 || day > 31 {
+				return nil, fmt.Errorf("invalid recurrence: day of month must be between 1 and 31, got %q", arg)
+			}
+		}
+		return &Recurrence{Kind: RecurMonthly, DayOfMonth: day}, nil
+	}
+
+	return nil, fmt.Errorf("invalid recurrence: %q (use daily, weekdays, weekly:mon,thu or monthly:15)", rule)
+}
+
+func (r Recurrence) String() string {
+	switch r.Kind {
+	case RecurWeekly:
+		days := make([]string, len(r.Weekdays))
+		for i, day := range r.Weekdays {
+			days[i] = day.String()[:3]
+		}
+		return "weekly on " + strings.Join(days, ", ")
+	case RecurMonthly:
+		return fmt.Sprintf("monthly on day %d", r.DayOfMonth)
+	}
+	return string(r.Kind)
+}
+
+// Rule formats the recurrence in the syntax accepted by ParseRecurrence.
+func (r Recurrence) Rule() string {
+	switch r.Kind {
+	case RecurWeekly:
+		days := make([]string, len(r.Weekdays))
+		for i, day := range r.Weekdays {
+			days[i] = strings.ToLower(day.String()[:3])
+		}
+		return "weekly:" + strings.Join(days, ",")
+	case RecurMonthly:
+		return fmt.Sprintf("monthly:%d", r.DayOfMonth)
+	}
+	return string(r.Kind)
+}
+
+// Matches reports whether the rule falls on the day containing t.
+func (r Recurrence) Matches(t time.Time) bool {
+	switch r.Kind {
+	case RecurDaily:
+		return true
+	case RecurWeekdays:
+		return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+	case RecurWeekly:
+		for _, day := range r.Weekdays {
+			if t.Weekday() == day {
+				return true
+			}
+		}
+		return false
+	case RecurMonthly:
+		lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		return t.Day() == min(r.DayOfMonth, lastDay)
+	}
+	return false
+}
+
+// First returns the start of the first day on or after t that the rule falls on.
+func (r Recurrence) First(t time.Time) time.Time {
+	day := StartOfDay(t)
+	if r.Matches(day) {
+		return day
+	}
+	return r.Next(day)
+}
+
+// Next returns the start of the first day strictly after t that the rule falls on.
+func (r Recurrence) Next(t time.Time) time.Time {
+	day := StartOfDay(t)
+	// Every rule matches at least once in any 31-day window.
+	for i := 0; i < 62; i++ {
+		day = day.AddDate(0, 0, 1)
+		if r.Matches(day) {
+			return day
+		}
+	}
+	return day
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SearchTasks returns every task, completed, archived or not, whose
+// description or one of whose tags matches pattern. Plain patterns are
+// case-insensitive substrings; with regex the pattern is a Go regular
+// expression matched as written.
+func (s *TaskStore) SearchTasks(pattern string, regex bool) ([]Task, error) {
+	match, err := searchMatcher(pattern, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []Task
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+
+		matches := func(task Task) error {
+			if match(task.Description) {
+				tasks = append(tasks, task)
+				return nil
+			}
+			for _, tag := range task.Tags {
+				if match(tag) {
+					tasks = append(tasks, task)
+					break
+				}
+			}
+			return nil
+		}
+
+		err := bucket.ForEach(func(k, v []byte) error {
+			var task Task
+			if err := task.UnmarshalBinary(v); err != nil {
+				return fmt.Errorf("failed to unmarshal task: %w", err)
+			}
+			return matches(task)
+		})
+		if err != nil {
+			return err
+		}
+		return forEachArchived(tx, matches)
+	})
+
+	return tasks, err
+}
+
+func searchMatcher(pattern string, regex bool) (func(string) bool, error) {
+	if regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return re.MatchString, nil
+	}
+
+	needle := strings.ToLower(pattern)
+	return func(s string) bool {
+		return strings.Contains(strings.ToLower(s), needle)
+	}, nil
+}
+
+// UpdateTask applies edit to the task with the given ID and saves the result
+// as a single undoable change. The ID cannot be changed.
+func (s *TaskStore) UpdateTask(id int, edit func(task *Task) error) (*Task, error) {
+	var task *Task
+
+	err := s.update("edit", func(m *mutation) error {
+		var err error
+		task, err = m.get(id)
+		if err != nil {
+			return err
+		}
+
+		if err := edit(task); err != nil {
+			return err
+		}
+		task.ID = id
+		task.Tags = NormalizeTags(task.Tags)
+
+		if strings.TrimSpace(task.Description) == "" {
+			return fmt.Errorf("task description cannot be empty")
+		}
+		return m.put(task)
+	})
+
+	return task, err
+}
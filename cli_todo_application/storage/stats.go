@@ -0,0 +1,69 @@
+package storage
+
+import "time"
+
+// DayCount is the number of tasks completed on one local calendar day.
+type DayCount struct {
+	Day   time.Time
+	Count int
+}
+
+// CompletionsPerDay counts completed tasks for every local day in [from, to).
+// Days without completions are included with a zero count.
+func CompletionsPerDay(tasks []Task, from, to time.Time) []DayCount {
+	counts := make(map[time.Time]int)
+	for _, task := range tasks {
+		if task.CompletedBetween(from, to) {
+			counts[StartOfDay(*task.CompletedAt)]++
+		}
+	}
+
+	var days []DayCount
+	for day := StartOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, DayCount{Day: day, Count: counts[day]})
+	}
+	return days
+}
+
+// AverageLeadTime returns the mean time from CreatedAt to CompletedAt over the
+// completed tasks, or zero if there are none.
+func AverageLeadTime(tasks []Task) time.Duration {
+	var total time.Duration
+	var n int
+	for _, task := range tasks {
+		if !task.Completed || task.CompletedAt == nil || task.CreatedAt.IsZero() {
+			continue
+		}
+		total += task.CompletedAt.Sub(task.CreatedAt)
+		n++
+	}
+
+	if n == 0 {
+		return 0
+	}
+	return total / time.Duration(n)
+}
+
+// CurrentStreak counts consecutive local days with at least one completion,
+// ending today. If nothing has been completed today yet, the streak ending
+// yesterday is still current.
+func CurrentStreak(tasks []Task, now time.Time) int {
+	active := make(map[time.Time]bool)
+	for _, task := range tasks {
+		if task.Completed && task.CompletedAt != nil {
+			active[StartOfDay(*task.CompletedAt)] = true
+		}
+	}
+
+	day := StartOfDay(now)
+	if !active[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	streak := 0
+	for active[day] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
@@ -2,9 +2,13 @@ package storage
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mitchellh/go-homedir"
@@ -14,25 +18,36 @@ import (
 const (
 	bucketName = "tasks"
 	dbFileName = "tasks.db"
-	appDir 	   = ".task"
+	appDir     = ".task"
+
+	// DatabaseEnvVar names the environment variable that overrides the
+	// database location.
+	DatabaseEnvVar = "TASK_DB"
+)
+
+var (
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrAmbiguousTask    = errors.New("ambiguous task reference")
+	ErrAlreadyCompleted = errors.New("task is already completed")
+	ErrDatabaseLocked   = errors.New("database is locked by another process")
 )
 
 type TaskStore struct {
 	db *bolt.DB
 }
 
-func NewTaskStore() (*TaskStore, error) {
-	dbPath, err := getDatabasePath()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get database path: %w", err)
-	}
-
+// NewTaskStore opens the database at dbPath, creating it and its directory if
+// needed. Use ResolveDatabasePath to pick the path.
+func NewTaskStore(dbPath string) (*TaskStore, error) {
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
 	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("failed to open database: %w", ErrDatabaseLocked)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -50,8 +65,15 @@ func NewTaskStore() (*TaskStore, error) {
 
 func (s *TaskStore) initBucket() error {
 	return s.db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(bucketName))
-		return err
+		for _, name := range []string{bucketName, journalBucketName, configBucketName, archiveBucketName} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		if err := initIndexes(tx); err != nil {
+			return err
+		}
+		return initUIDs(tx)
 	})
 }
 
@@ -62,6 +84,52 @@ func (s *TaskStore) Close() error {
 	return s.db.Close()
 }
 
+// ResolveDatabasePath picks the database to use. In order of precedence it is
+// override (from the --db flag), the TASK_DB environment variable, a
+// .task/tasks.db in the current directory or any of its parents, and finally
+// ~/.task/tasks.db.
+func ResolveDatabasePath(override string) (string, error) {
+	if override != "" {
+		return homedir.Expand(override)
+	}
+	if env := os.Getenv(DatabaseEnvVar); env != "" {
+		return homedir.Expand(env)
+	}
+
+	if found, ok, err := findLocalDatabase(); err != nil {
+		return "", err
+	} else if ok {
+		return found, nil
+	}
+
+	return getDatabasePath()
+}
+
+// LocalDatabasePath returns where a per-directory database lives for dir.
+func LocalDatabasePath(dir string) string {
+	return filepath.Join(dir, appDir, dbFileName)
+}
+
+func findLocalDatabase() (string, bool, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	for {
+		candidate := LocalDatabasePath(dir)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false, nil
+		}
+		dir = parent
+	}
+}
+
 func getDatabasePath() (string, error) {
 	homeDir, err := homedir.Dir()
 	if err != nil {
@@ -77,58 +145,157 @@ func itob(v int) []byte {
 	return b
 }
 
-func (s *TaskStore) AddTask(description string) (*Task, error) {
+func btoi(b []byte) int {
+	return int(binary.BigEndian.Uint64(b))
+}
+
+func readTask(tx *bolt.Tx, id int) (*Task, error) {
+	bucket := tx.Bucket([]byte(bucketName))
+	if bucket == nil {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	data := bucket.Get(itob(id))
+	if data == nil {
+		return nil, fmt.Errorf("%w: %d", ErrTaskNotFound, id)
+	}
+
+	var task Task
+	if err := task.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+	}
+	return &task, nil
+}
+
+// writeTask stores task and keeps the indexes in step with it. Tasks without
+// a UID are given one. All writes to the tasks bucket go through writeTask and
+// removeTask.
+func writeTask(tx *bolt.Tx, task *Task) error {
+	bucket := tx.Bucket([]byte(bucketName))
+	if bucket == nil {
+		return fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	if task.UID == "" {
+		uid, err := newUID()
+		if err != nil {
+			return err
+		}
+		task.UID = uid
+	}
+	task.Archived = false
+	if err := unarchiveTask(tx, task.ID); err != nil {
+		return err
+	}
+
+	old, err := readTask(tx, task.ID)
+	if err != nil && !errors.Is(err, ErrTaskNotFound) {
+		return err
+	}
+
+	data, err := task.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	if err := bucket.Put(itob(task.ID), data); err != nil {
+		return err
+	}
+	return reindexTask(tx, old, task)
+}
+
+func removeTask(tx *bolt.Tx, id int) error {
+	bucket := tx.Bucket([]byte(bucketName))
+	if bucket == nil {
+		return fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	old, err := readTask(tx, id)
+	if errors.Is(err, ErrTaskNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := bucket.Delete(itob(id)); err != nil {
+		return err
+	}
+	return reindexTask(tx, old, nil)
+}
+
+// applyState sets a task to the given state, deleting it when state is nil.
+func applyState(tx *bolt.Tx, id int, state *Task) error {
+	if state == nil {
+		return removeTask(tx, id)
+	}
+	return writeTask(tx, state)
+}
+
+// AddTask stores a new task built from the given template. The ID, completion
+// state and creation time are assigned by the store, and tasks without a
+// project go into the default project. Subtasks always join their parent's
+// project.
+func (s *TaskStore) AddTask(template Task) (*Task, error) {
 	var task *Task
 
-	err := s.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", bucketName)
+	err := s.update("add", func(m *mutation) error {
+		project := template.Project
+		if project == "" {
+			project = readDefaultProject(m.tx)
 		}
 
-		id, err := bucket.NextSequence()
+		if template.ParentID != 0 {
+			parent, err := m.get(template.ParentID)
+			if err != nil {
+				return fmt.Errorf("invalid parent: %w", err)
+			}
+			if parent.Completed {
+				return fmt.Errorf("invalid parent: %w: %d", ErrAlreadyCompleted, parent.ID)
+			}
+			project = parent.Project
+		}
+		for _, blockerID := range template.BlockedBy {
+			if _, err := m.get(blockerID); err != nil {
+				return fmt.Errorf("invalid blocker: %w", err)
+			}
+		}
+
+		id, err := m.nextID()
 		if err != nil {
-			return fmt.Errorf("failed to generate ID: %w", err)
+			return err
 		}
 
 		task = &Task{
-			ID:          int(id),
-			Description: description,
+			ID:          id,
+			Project:     project,
+			Description: template.Description,
 			Completed:   false,
 			CreatedAt:   time.Now(),
+			Due:         template.Due,
+			Priority:    template.Priority,
+			Tags:        NormalizeTags(template.Tags),
+			Recurrence:  template.Recurrence,
+			ParentID:    template.ParentID,
+			BlockedBy:   template.BlockedBy,
 		}
-
-		data, err := task.MarshalBinary()
-		if err != nil {
-			return fmt.Errorf("failed to marshal task: %w", err)
+		if task.Recurrence != nil {
+			task.SeriesID = task.ID
 		}
 
-		key := itob(task.ID)
-		return bucket.Put(key, data)
+		return m.put(task)
 	})
 
 	return task, err
 }
 
+// GetIncompleteTasks returns every incomplete task, in ID order. It reads
+// the open-task index, so its cost does not grow with completed history.
 func (s *TaskStore) GetIncompleteTasks() ([]Task, error) {
 	var tasks []Task
 
 	err := s.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return nil // No tasks yet
-		}
-
-		return bucket.ForEach(func(k, v []byte) error {
-			var task Task
-			if err := task.UnmarshalBinary(v); err != nil {
-				return fmt.Errorf("failed to unmarshal task: %w", err)
-			}
-
-			if !task.Completed {
-				tasks = append(tasks, task)
-			}
-
+		return forEachOpen(tx, func(task Task) error {
+			tasks = append(tasks, task)
 			return nil
 		})
 	})
@@ -136,8 +303,23 @@ func (s *TaskStore) GetIncompleteTasks() ([]Task, error) {
 	return tasks, err
 }
 
-func (s *TaskStore) CompleteTask(position int) (*Task, error) {
-	incompleteTasks, err := s.GetIncompleteTasks()
+// GetTask looks up a task by its persistent ID.
+func (s *TaskStore) GetTask(id int) (*Task, error) {
+	var task *Task
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		task, err = readTask(tx, id)
+		return err
+	})
+
+	return task, err
+}
+
+// TaskAtPosition resolves a 1-based position in a project's incomplete task
+// list. Positions shift whenever tasks are added or removed, so prefer IDs.
+func (s *TaskStore) TaskAtPosition(project string, position int) (*Task, error) {
+	incompleteTasks, err := s.GetProjectTasks(project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get incomplete tasks: %w", err)
 	}
@@ -147,54 +329,160 @@ func (s *TaskStore) CompleteTask(position int) (*Task, error) {
 	}
 
 	task := incompleteTasks[position-1]
-	task.Completed = true
-	now := time.Now()
-	task.CompletedAt = &now
+	return &task, nil
+}
 
-	err = s.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", bucketName)
-		}
+// FindTaskByPrefix returns the single incomplete task whose description starts
+// with prefix, ignoring case. It fails if no task or more than one task matches.
+func (s *TaskStore) FindTaskByPrefix(prefix string) (*Task, error) {
+	incompleteTasks, err := s.GetIncompleteTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incomplete tasks: %w", err)
+	}
+	return matchPrefix(incompleteTasks, prefix)
+}
 
-		data, err := task.MarshalBinary()
-		if err != nil {
-			return fmt.Errorf("failed to marshal task: %w", err)
+// matchPrefix returns the single task in tasks whose description starts with
+// prefix, ignoring case.
+func matchPrefix(tasks []Task, prefix string) (*Task, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return nil, fmt.Errorf("%w: empty description prefix", ErrTaskNotFound)
+	}
+
+	var matches []Task
+	for _, task := range tasks {
+		if strings.HasPrefix(strings.ToLower(task.Description), prefix) {
+			matches = append(matches, task)
 		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: no task starts with %q", ErrTaskNotFound, prefix)
+	case 1:
+		return &matches[0], nil
+	}
+
+	ids := make([]string, len(matches))
+	for i, task := range matches {
+		ids[i] = strconv.Itoa(task.ID)
+	}
+	return nil, fmt.Errorf("%w: %q matches tasks %s", ErrAmbiguousTask, prefix, strings.Join(ids, ", "))
+}
+
+// ResolveTask interprets ref as a task ID if it is numeric, and as a unique
+// description prefix otherwise.
+func (s *TaskStore) ResolveTask(ref string) (*Task, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return s.GetTask(id)
+	}
+	return s.FindTaskByPrefix(ref)
+}
 
-		key := itob(task.ID)
-		return bucket.Put(key, data)
+// CompleteTask marks the task with the given ID as complete. Completing an
+// instance of a recurring task also creates the next instance in the same
+// transaction and links it through NextID.
+//
+// A task with open subtasks cannot be completed unless force is set, in which
+// case the open subtasks are completed along with it.
+func (s *TaskStore) CompleteTask(id int, force bool) (*Task, error) {
+	var task *Task
+
+	err := s.update("complete", func(m *mutation) error {
+		var err error
+		task, err = m.complete(id, force, time.Now())
+		return err
 	})
 
-	return &task, err
+	return task, err
 }
 
-func (s *TaskStore) DeleteTask(position int) (*Task, error) {
-	incompleteTasks, err := s.GetIncompleteTasks()
+// complete marks a task as complete, creating its next occurrence if it
+// recurs. With force its open subtasks are completed too.
+func (m *mutation) complete(id int, force bool, now time.Time) (*Task, error) {
+	task, err := m.get(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get incomplete tasks: %w", err)
+		return nil, err
+	}
+	if task.Completed {
+		return nil, fmt.Errorf("%w: %d", ErrAlreadyCompleted, id)
 	}
 
-	if position < 1 || position > len(incompleteTasks) {
-		return nil, fmt.Errorf("invalid task number: %d (must be between 1 and %d)", position, len(incompleteTasks))
+	all, err := m.all()
+	if err != nil {
+		return nil, err
+	}
+	var openChildren []string
+	for _, other := range all {
+		if other.ParentID == id && !other.Completed {
+			openChildren = append(openChildren, strconv.Itoa(other.ID))
+		}
 	}
 
-	task := incompleteTasks[position-1]
+	if len(openChildren) > 0 && !force {
+		return nil, fmt.Errorf("%w: %s", ErrOpenSubtasks, strings.Join(openChildren, ", "))
+	}
 
-	err = s.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return fmt.Errorf("bucket %s not found", bucketName)
+	task.Completed = true
+	task.CompletedAt = &now
+	task.stopTimer(now)
+	if err := m.put(task); err != nil {
+		return nil, err
+	}
+
+	if task.Recurrence != nil {
+		next, err := m.addNextOccurrence(task, now)
+		if err != nil {
+			return nil, err
 		}
+		task.NextID = next.ID
+		if err := m.put(task); err != nil {
+			return nil, err
+		}
+	}
 
-		key := itob(task.ID)
-		return bucket.Delete(key)
+	return task, m.completeSubtree(all, id, now)
+}
+
+// DeleteTask removes the task with the given ID. Its subtasks move up to its
+// parent and tasks it was blocking are unblocked.
+func (s *TaskStore) DeleteTask(id int) (*Task, error) {
+	var task *Task
+
+	err := s.update("delete", func(m *mutation) error {
+		var err error
+		task, err = m.deleteTask(id)
+		return err
 	})
 
-	return &task, err
+	return task, err
+}
+
+// deleteTask removes a task and detaches it from its subtasks and the tasks
+// it was blocking.
+func (m *mutation) deleteTask(id int) (*Task, error) {
+	task, err := m.delete(id)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := m.all()
+	if err != nil {
+		return nil, err
+	}
+	return task, m.detach(all, task)
 }
 
 func (s *TaskStore) GetCompletedTasksToday() ([]Task, error) {
+	today := StartOfDay(time.Now())
+	return s.GetCompletedTasks(today, today.AddDate(0, 0, 1))
+}
+
+// GetCompletedTasks returns tasks completed in the half-open interval
+// [from, to), including archived ones, ordered by completion time. A zero
+// from or to leaves that end of the range open.
+func (s *TaskStore) GetCompletedTasks(from, to time.Time) ([]Task, error) {
 	var tasks []Task
 
 	err := s.db.View(func(tx *bolt.Tx) error {
@@ -203,19 +491,66 @@ func (s *TaskStore) GetCompletedTasksToday() ([]Task, error) {
 			return nil
 		}
 
-		return bucket.ForEach(func(k, v []byte) error {
+		err := bucket.ForEach(func(k, v []byte) error {
 			var task Task
 			if err := task.UnmarshalBinary(v); err != nil {
 				return fmt.Errorf("failed to unmarshal task: %w", err)
 			}
 
-			if task.IsCompletedToday() {
+			if task.CompletedBetween(from, to) {
 				tasks = append(tasks, task)
 			}
 
 			return nil
 		})
+		if err != nil {
+			return err
+		}
+
+		return forEachArchived(tx, func(task Task) error {
+			if task.CompletedBetween(from, to) {
+				tasks = append(tasks, task)
+			}
+			return nil
+		})
 	})
 
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CompletedAt.Before(*tasks[j].CompletedAt)
+	})
 	return tasks, err
-}
\ No newline at end of file
+}
+
+// addNextOccurrence creates the instance of a recurring task that follows
+// task. The next due date is counted from the later of the current due date
+// and the completion day, so finishing late does not create overdue instances.
+func (m *mutation) addNextOccurrence(task *Task, completedAt time.Time) (*Task, error) {
+	from := StartOfDay(completedAt)
+	if task.Due != nil && task.Due.After(from) {
+		from = *task.Due
+	}
+	due := task.Recurrence.Next(from)
+
+	id, err := m.nextID()
+	if err != nil {
+		return nil, err
+	}
+
+	seriesID := task.SeriesID
+	if seriesID == 0 {
+		seriesID = task.ID
+	}
+
+	next := &Task{
+		ID:          id,
+		Project:     task.Project,
+		Description: task.Description,
+		CreatedAt:   completedAt,
+		Due:         &due,
+		Priority:    task.Priority,
+		Tags:        task.Tags,
+		Recurrence:  task.Recurrence,
+		SeriesID:    seriesID,
+	}
+	return next, m.put(next)
+}
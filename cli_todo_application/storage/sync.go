@@ -0,0 +1,856 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	syncBucketName      = "sync"
+	syncStatesBucket    = "states"
+	syncOffsetsBucket   = "offsets"
+	syncMachineKey      = "machine"
+	syncDirKey          = "sync_dir"
+	syncPendingKey      = "pending"
+	uidsBackfilledKey   = "uids_backfilled"
+	changeLogExtension  = ".jsonl"
+	deletedFieldName    = "deleted"
+	machineSuffixLength = 3
+)
+
+// Change is one line of a machine's change log: new values for some fields
+// of a task, or its deletion. Tasks are identified by UID because local IDs
+// differ between databases.
+type Change struct {
+	UID     string                     `json:"uid"`
+	Machine string                     `json:"machine"`
+	Time    time.Time                  `json:"time"`
+	Fields  map[string]json.RawMessage `json:"fields,omitempty"`
+	Deleted bool                       `json:"deleted,omitempty"`
+}
+
+// SyncConflict is a field changed both locally and on another machine since
+// the last sync. The change made last wins.
+type SyncConflict struct {
+	Task          Task   `json:"task"`
+	Field         string `json:"field"`
+	Local         string `json:"local"`
+	Remote        string `json:"remote"`
+	RemoteMachine string `json:"remote_machine"`
+	RemoteWon     bool   `json:"remote_won"`
+	// Deleted is set when the task was deleted on one side and edited on
+	// the other; the deletion always wins.
+	Deleted bool `json:"deleted"`
+}
+
+// SyncResult summarizes a sync.
+type SyncResult struct {
+	Machine   string         `json:"machine"`
+	Sent      int            `json:"sent"`
+	Received  int            `json:"received"`
+	Added     []Task         `json:"added"`
+	Updated   []Task         `json:"updated"`
+	Deleted   []Task         `json:"deleted"`
+	Conflicts []SyncConflict `json:"conflicts"`
+}
+
+// fieldClock records when a field last changed and on which machine.
+type fieldClock struct {
+	Time    time.Time `json:"time"`
+	Machine string    `json:"machine"`
+}
+
+func (c fieldClock) before(t time.Time, machine string) bool {
+	return c.Time.Before(t) || (c.Time.Equal(t) && c.Machine < machine)
+}
+
+// syncState is the agreed state of a task as of the last sync, with the clock
+// of every field. Deleted tasks keep their state so they are not revived by
+// older changes.
+type syncState struct {
+	Fields  map[string]json.RawMessage `json:"fields"`
+	Clocks  map[string]fieldClock      `json:"clocks"`
+	Deleted bool                       `json:"deleted,omitempty"`
+}
+
+// syncTask is the machine-independent form of a task that change logs carry.
+// References to other tasks use UIDs.
+type syncTask struct {
+	Description string      `json:"description"`
+	Project     string      `json:"project"`
+	Completed   bool        `json:"completed"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at"`
+	Due         *time.Time  `json:"due"`
+	Priority    Priority    `json:"priority"`
+	Tags        []string    `json:"tags"`
+	Recurrence  *Recurrence `json:"recurrence"`
+	Series      string      `json:"series"`
+	Next        string      `json:"next"`
+	Parent      string      `json:"parent"`
+	BlockedBy   []string    `json:"blocked_by"`
+	TimeEntries []TimeEntry `json:"time_entries"`
+}
+
+func newUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate UID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// initUIDs gives every task created before UIDs existed a UID, once.
+func initUIDs(tx *bolt.Tx) error {
+	config := tx.Bucket([]byte(configBucketName))
+	if config.Get([]byte(uidsBackfilledKey)) != nil {
+		return nil
+	}
+
+	var missing []Task
+	err := tx.Bucket([]byte(bucketName)).ForEach(func(k, v []byte) error {
+		var task Task
+		if err := task.UnmarshalBinary(v); err != nil {
+			return fmt.Errorf("failed to unmarshal task: %w", err)
+		}
+		if task.UID == "" {
+			missing = append(missing, task)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range missing {
+		if err := writeTask(tx, &missing[i]); err != nil {
+			return err
+		}
+	}
+	return config.Put([]byte(uidsBackfilledKey), []byte("1"))
+}
+
+// forgetSyncState drops the sync state of a task that no longer exists
+// locally without treating it as deleted, so that purging a task here does
+// not delete it on other machines.
+func forgetSyncState(tx *bolt.Tx, uid string) error {
+	bucket := tx.Bucket([]byte(syncBucketName))
+	if bucket == nil {
+		return nil
+	}
+	states := bucket.Bucket([]byte(syncStatesBucket))
+	if states == nil {
+		return nil
+	}
+	return states.Delete([]byte(uid))
+}
+
+// SyncDir returns the directory configured for "task sync", if any.
+func (s *TaskStore) SyncDir() (string, error) {
+	var dir string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte(configBucketName)).Get([]byte(syncDirKey)); value != nil {
+			dir = string(value)
+		}
+		return nil
+	})
+
+	return dir, err
+}
+
+func (s *TaskStore) SetSyncDir(dir string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(configBucketName)).Put([]byte(syncDirKey), []byte(dir))
+	})
+}
+
+// Sync exchanges changes with other machines through dir. Local changes made
+// since the last sync are appended to this machine's change log in dir, then
+// the logs of every other machine are merged in, field by field, keeping the
+// most recent change. Deletions win over edits. The merge is journaled as a
+// single "sync" operation.
+//
+// Local changes are only appended to the log once the merge has committed.
+// Until they are written they are kept as pending in the database, so a
+// failed write is retried by the next sync. Appending a change twice is
+// harmless: the copy loses to the original.
+func (s *TaskStore) Sync(dir string) (*SyncResult, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sync directory: %w", err)
+	}
+
+	result := &SyncResult{}
+	var pending []byte
+
+	err := s.update("sync", func(m *mutation) error {
+		bucket, err := m.tx.CreateBucketIfNotExists([]byte(syncBucketName))
+		if err != nil {
+			return err
+		}
+		states, err := bucket.CreateBucketIfNotExists([]byte(syncStatesBucket))
+		if err != nil {
+			return err
+		}
+		offsets, err := bucket.CreateBucketIfNotExists([]byte(syncOffsetsBucket))
+		if err != nil {
+			return err
+		}
+
+		if result.Machine, err = machineID(bucket); err != nil {
+			return err
+		}
+
+		sc, err := newSyncContext(m, states, result)
+		if err != nil {
+			return err
+		}
+
+		outgoing, err := sc.collectLocalChanges()
+		if err != nil {
+			return err
+		}
+		encoded, err := encodeChanges(outgoing)
+		if err != nil {
+			return err
+		}
+		pending = append(bytes.Clone(bucket.Get([]byte(syncPendingKey))), encoded...)
+		if len(pending) > 0 {
+			if err := bucket.Put([]byte(syncPendingKey), pending); err != nil {
+				return err
+			}
+		}
+		result.Sent = len(outgoing)
+
+		incoming, err := readChangeLogs(dir, result.Machine, offsets)
+		if err != nil {
+			return err
+		}
+		for _, change := range incoming {
+			if err := sc.merge(change); err != nil {
+				return err
+			}
+		}
+		result.Received = len(incoming)
+
+		if err := sc.apply(); err != nil {
+			return err
+		}
+		return sc.save()
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, s.flushChanges(filepath.Join(dir, result.Machine+changeLogExtension), pending)
+}
+
+// flushChanges appends the pending changes to this machine's change log and
+// then clears them.
+func (s *TaskStore) flushChanges(path string, pending []byte) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := appendChangeLog(path, pending); err != nil {
+		return fmt.Errorf("%w; the changes will be written by the next sync", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(syncBucketName)).Delete([]byte(syncPendingKey))
+	})
+}
+
+// machineID returns this database's machine name, creating it on first use
+// from the host name and a random suffix.
+func machineID(bucket *bolt.Bucket) (string, error) {
+	if id := bucket.Get([]byte(syncMachineKey)); id != nil {
+		return string(id), nil
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "machine"
+	}
+	host = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(host))
+
+	suffix := make([]byte, machineSuffixLength)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate machine ID: %w", err)
+	}
+
+	id := host + "-" + hex.EncodeToString(suffix)
+	return id, bucket.Put([]byte(syncMachineKey), []byte(id))
+}
+
+// syncContext holds the state of one sync while it runs.
+type syncContext struct {
+	m      *mutation
+	saved  *bolt.Bucket
+	result *SyncResult
+	now    time.Time
+	tasks  []Task
+	byUID  map[string]*Task
+	uidOf  map[int]string
+	states map[string]*syncState
+	// archived holds the UIDs of archived tasks. They are frozen: they are
+	// neither reported as deleted nor changed by other machines.
+	archived map[string]bool
+	local    map[string]map[string]bool
+	touched  map[string]bool
+}
+
+func newSyncContext(m *mutation, saved *bolt.Bucket, result *SyncResult) (*syncContext, error) {
+	tasks, err := m.all()
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &syncContext{
+		m:        m,
+		saved:    saved,
+		result:   result,
+		now:      time.Now(),
+		tasks:    tasks,
+		byUID:    make(map[string]*Task, len(tasks)),
+		uidOf:    make(map[int]string, len(tasks)),
+		states:   make(map[string]*syncState),
+		archived: make(map[string]bool),
+		local:    make(map[string]map[string]bool),
+		touched:  make(map[string]bool),
+	}
+	for i := range tasks {
+		sc.byUID[tasks[i].UID] = &tasks[i]
+		sc.uidOf[tasks[i].ID] = tasks[i].UID
+	}
+
+	err = forEachArchived(m.tx, func(task Task) error {
+		sc.archived[task.UID] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = saved.ForEach(func(k, v []byte) error {
+		var state syncState
+		if err := json.Unmarshal(v, &state); err != nil {
+			return fmt.Errorf("failed to unmarshal sync state: %w", err)
+		}
+		sc.states[string(k)] = &state
+		return nil
+	})
+	return sc, err
+}
+
+func (sc *syncContext) state(uid string) *syncState {
+	state, ok := sc.states[uid]
+	if !ok {
+		state = &syncState{Fields: make(map[string]json.RawMessage), Clocks: make(map[string]fieldClock)}
+		sc.states[uid] = state
+	}
+	return state
+}
+
+// collectLocalChanges compares every task with its state at the last sync and
+// returns the differences as changes, updating the states to match.
+func (sc *syncContext) collectLocalChanges() ([]Change, error) {
+	machine := sc.result.Machine
+	var changes []Change
+
+	for i := range sc.tasks {
+		task := &sc.tasks[i]
+
+		if state, ok := sc.states[task.UID]; ok && state.Deleted {
+			// The task was deleted elsewhere and then restored here, for
+			// example by undo. Deletions are final, so it becomes a new task.
+			delete(sc.byUID, task.UID)
+			task.UID = ""
+			if err := sc.m.putAt(task, task.UpdatedAt); err != nil {
+				return nil, err
+			}
+			sc.byUID[task.UID] = task
+			sc.uidOf[task.ID] = task.UID
+		}
+	}
+
+	for i := range sc.tasks {
+		task := &sc.tasks[i]
+		fields, err := sc.fields(task)
+		if err != nil {
+			return nil, err
+		}
+
+		state := sc.state(task.UID)
+		change := Change{UID: task.UID, Machine: machine, Time: task.UpdatedAt, Fields: make(map[string]json.RawMessage)}
+		if change.Time.IsZero() {
+			change.Time = sc.now
+		}
+
+		for name, value := range fields {
+			if old, ok := state.Fields[name]; ok && bytes.Equal(old, value) {
+				continue
+			}
+			change.Fields[name] = value
+			// A change must win over the value it replaces, even if the task
+			// was restored to an older state.
+			if clock, ok := state.Clocks[name]; ok && !change.Time.After(clock.Time) {
+				change.Time = clock.Time.Add(time.Nanosecond)
+			}
+		}
+		if len(change.Fields) == 0 {
+			continue
+		}
+
+		sc.local[task.UID] = make(map[string]bool, len(change.Fields))
+		for name, value := range change.Fields {
+			state.Fields[name] = value
+			state.Clocks[name] = fieldClock{Time: change.Time, Machine: machine}
+			sc.local[task.UID][name] = true
+		}
+		changes = append(changes, change)
+	}
+
+	uids := make([]string, 0, len(sc.states))
+	for uid := range sc.states {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	for _, uid := range uids {
+		state := sc.states[uid]
+		if state.Deleted || sc.byUID[uid] != nil || sc.archived[uid] {
+			continue
+		}
+		state.Deleted = true
+		state.Clocks[deletedFieldName] = fieldClock{Time: sc.now, Machine: machine}
+		sc.local[uid] = map[string]bool{deletedFieldName: true}
+		changes = append(changes, Change{UID: uid, Machine: machine, Time: sc.now, Deleted: true})
+	}
+
+	return changes, nil
+}
+
+// merge folds a change from another machine into the sync states.
+func (sc *syncContext) merge(change Change) error {
+	if sc.archived[change.UID] {
+		return nil
+	}
+
+	state := sc.state(change.UID)
+	if state.Deleted {
+		if sc.local[change.UID][deletedFieldName] {
+			for name, value := range change.Fields {
+				if bytes.Equal(state.Fields[name], value) {
+					continue
+				}
+				if err := sc.conflict(change, name, deletedFieldName, string(value), false); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if change.Deleted {
+		state.Deleted = true
+		state.Clocks[deletedFieldName] = fieldClock{Time: change.Time, Machine: change.Machine}
+		sc.touched[change.UID] = true
+
+		for name := range sc.local[change.UID] {
+			if err := sc.conflict(change, name, string(state.Fields[name]), deletedFieldName, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for name, value := range change.Fields {
+		clock, known := state.Clocks[name]
+		remoteWins := !known || clock.before(change.Time, change.Machine)
+		differs := !bytes.Equal(state.Fields[name], value)
+
+		if sc.local[change.UID][name] && differs {
+			if err := sc.conflict(change, name, string(state.Fields[name]), string(value), remoteWins); err != nil {
+				return err
+			}
+		}
+
+		if remoteWins {
+			state.Fields[name] = value
+			state.Clocks[name] = fieldClock{Time: change.Time, Machine: change.Machine}
+			if differs {
+				sc.touched[change.UID] = true
+			}
+		}
+	}
+	return nil
+}
+
+// conflict reports a field that change and a local change both modified.
+func (sc *syncContext) conflict(change Change, field, local, remote string, remoteWon bool) error {
+	conflict := SyncConflict{
+		Field: field, Local: local, Remote: remote,
+		RemoteMachine: change.Machine, RemoteWon: remoteWon,
+		Deleted: local == deletedFieldName || remote == deletedFieldName,
+	}
+	if task := sc.byUID[change.UID]; task != nil {
+		conflict.Task = *task
+	} else {
+		// The task was deleted here; only its synced state is left.
+		if description := sc.states[change.UID].Fields["description"]; description != nil {
+			if err := json.Unmarshal(description, &conflict.Task.Description); err != nil {
+				return fmt.Errorf("failed to unmarshal description of %s: %w", change.UID, err)
+			}
+		}
+	}
+	sc.result.Conflicts = append(sc.result.Conflicts, conflict)
+	return nil
+}
+
+// apply writes the merged state of every task touched by remote changes to
+// the tasks bucket.
+func (sc *syncContext) apply() error {
+	uids := make([]string, 0, len(sc.touched))
+	for uid := range sc.touched {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	// New tasks need IDs before references to them can be resolved. They
+	// are numbered in the order they were created.
+	var created []string
+	createdAt := make(map[string]time.Time)
+	for _, uid := range uids {
+		if sc.states[uid].Deleted || sc.byUID[uid] != nil {
+			continue
+		}
+		if raw := sc.states[uid].Fields["created_at"]; raw != nil {
+			var t time.Time
+			if err := json.Unmarshal(raw, &t); err != nil {
+				return fmt.Errorf("failed to unmarshal creation time of %s: %w", uid, err)
+			}
+			createdAt[uid] = t
+		}
+		created = append(created, uid)
+	}
+	sort.SliceStable(created, func(i, j int) bool {
+		return createdAt[created[i]].Before(createdAt[created[j]])
+	})
+	for _, uid := range created {
+		id, err := sc.m.nextID()
+		if err != nil {
+			return err
+		}
+		task := &Task{ID: id, UID: uid}
+		sc.byUID[uid] = task
+		sc.uidOf[id] = uid
+	}
+
+	for _, uid := range uids {
+		state := sc.states[uid]
+		task := sc.byUID[uid]
+		if task == nil {
+			continue
+		}
+
+		if state.Deleted {
+			deleted, err := sc.m.delete(task.ID)
+			if err != nil {
+				return err
+			}
+			all, err := sc.m.all()
+			if err != nil {
+				return err
+			}
+			if err := sc.m.detach(all, deleted); err != nil {
+				return err
+			}
+			sc.result.Deleted = append(sc.result.Deleted, *deleted)
+			continue
+		}
+
+		isNew := task.CreatedAt.IsZero()
+		if err := sc.setFields(task, state.Fields); err != nil {
+			return err
+		}
+
+		var updated time.Time
+		for _, clock := range state.Clocks {
+			if clock.Time.After(updated) {
+				updated = clock.Time
+			}
+		}
+		if err := sc.m.putAt(task, updated); err != nil {
+			return err
+		}
+
+		if isNew {
+			sc.result.Added = append(sc.result.Added, *task)
+		} else {
+			sc.result.Updated = append(sc.result.Updated, *task)
+		}
+	}
+
+	for _, tasks := range [][]Task{sc.result.Added, sc.result.Updated, sc.result.Deleted} {
+		sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	}
+	return nil
+}
+
+// save stores the sync states. References that could not be resolved locally
+// are recorded as they ended up, so they are not sent back as local changes.
+func (sc *syncContext) save() error {
+	for uid, state := range sc.states {
+		if task := sc.byUID[uid]; task != nil && sc.touched[uid] && !state.Deleted {
+			fields, err := sc.fields(task)
+			if err != nil {
+				return err
+			}
+			state.Fields = fields
+		}
+
+		data, err := json.Marshal(state)
+		if err != nil {
+			return fmt.Errorf("failed to marshal sync state: %w", err)
+		}
+		if err := sc.saved.Put([]byte(uid), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fields returns the machine-independent fields of task, encoded as JSON.
+func (sc *syncContext) fields(task *Task) (map[string]json.RawMessage, error) {
+	portable := syncTask{
+		Description: task.Description,
+		Project:     task.ProjectName(),
+		Completed:   task.Completed,
+		CreatedAt:   task.CreatedAt.UTC(),
+		CompletedAt: utcTime(task.CompletedAt),
+		Due:         utcTime(task.Due),
+		Priority:    task.Priority,
+		Recurrence:  task.Recurrence,
+		Series:      sc.uidOf[task.SeriesID],
+		Next:        sc.uidOf[task.NextID],
+		Parent:      sc.uidOf[task.ParentID],
+	}
+	if len(task.Tags) > 0 {
+		portable.Tags = task.Tags
+	}
+	for _, entry := range task.TimeEntries {
+		portable.TimeEntries = append(portable.TimeEntries, TimeEntry{Start: entry.Start.UTC(), End: utcTime(entry.End)})
+	}
+	for _, id := range task.BlockedBy {
+		if uid := sc.uidOf[id]; uid != "" {
+			portable.BlockedBy = append(portable.BlockedBy, uid)
+		}
+	}
+
+	data, err := json.Marshal(portable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+	}
+	return fields, nil
+}
+
+// setFields overwrites the synced fields of task with fields, resolving UIDs
+// to local IDs. References to unknown tasks are dropped.
+func (sc *syncContext) setFields(task *Task, fields map[string]json.RawMessage) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	var portable syncTask
+	if err := json.Unmarshal(data, &portable); err != nil {
+		return fmt.Errorf("failed to unmarshal task: %w", err)
+	}
+
+	idOf := func(uid string) int {
+		if other := sc.byUID[uid]; uid != "" && other != nil {
+			return other.ID
+		}
+		return 0
+	}
+
+	task.Description = portable.Description
+	task.Project = portable.Project
+	task.Completed = portable.Completed
+	task.CreatedAt = portable.CreatedAt.Local()
+	task.CompletedAt = localTime(portable.CompletedAt)
+	task.Due = localTime(portable.Due)
+	task.Priority = portable.Priority
+	task.Tags = NormalizeTags(portable.Tags)
+	task.Recurrence = portable.Recurrence
+	task.SeriesID = idOf(portable.Series)
+	task.NextID = idOf(portable.Next)
+	task.ParentID = idOf(portable.Parent)
+	task.TimeEntries = nil
+	for _, entry := range portable.TimeEntries {
+		task.TimeEntries = append(task.TimeEntries, TimeEntry{Start: entry.Start.Local(), End: localTime(entry.End)})
+	}
+	task.BlockedBy = nil
+	for _, uid := range portable.BlockedBy {
+		if id := idOf(uid); id != 0 {
+			task.BlockedBy = append(task.BlockedBy, id)
+		}
+	}
+	return nil
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func localTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	l := t.Local()
+	return &l
+}
+
+// encodeChanges encodes changes as lines of a change log, one JSON object per
+// line.
+func encodeChanges(changes []Change) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, change := range changes {
+		if err := encoder.Encode(change); err != nil {
+			return nil, fmt.Errorf("failed to marshal change: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// appendChangeLog appends encoded changes to a change log.
+func appendChangeLog(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open change log: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write change log: %w", err)
+	}
+	return file.Close()
+}
+
+// readChangeLogs reads the changes other machines appended to their logs in
+// dir since the offsets recorded in offsets, and advances the offsets. A
+// trailing line without a newline is left for the next sync, since the file
+// may still be arriving.
+func readChangeLogs(dir, machine string, offsets *bolt.Bucket) ([]Change, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+changeLogExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, path := range paths {
+		other := strings.TrimSuffix(filepath.Base(path), changeLogExtension)
+		if other == machine {
+			continue
+		}
+
+		var offset int64
+		if value := offsets.Get([]byte(other)); value != nil {
+			offset = int64(btoi(value))
+		}
+
+		read, n, err := readChangeLog(path, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read change log %s: %w", filepath.Base(path), err)
+		}
+		changes = append(changes, read...)
+
+		if err := offsets.Put([]byte(other), itob(int(offset+n))); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func readChangeLog(path string, offset int64) ([]Change, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	var changes []Change
+	var n int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return changes, n, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		n += int64(len(line))
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var change Change
+		err = json.Unmarshal(line, &change)
+		if err == nil {
+			err = change.validate()
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid change at offset %d: %w", offset+n-int64(len(line)), err)
+		}
+		changes = append(changes, change)
+	}
+}
+
+// validate checks that a change read from a log identifies its task and
+// carries fields of the right types, so that a damaged line is not merged
+// as an empty task.
+func (c Change) validate() error {
+	if c.UID == "" || c.Machine == "" || c.Time.IsZero() {
+		return errors.New("missing uid, machine or time")
+	}
+	if c.Deleted {
+		return nil
+	}
+	if len(c.Fields) == 0 {
+		return errors.New("no fields")
+	}
+	data, err := json.Marshal(c.Fields)
+	if err != nil {
+		return err
+	}
+	var portable syncTask
+	return json.Unmarshal(data, &portable)
+}
@@ -3,15 +3,86 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+var priorityNames = map[Priority]string{
+	PriorityNone:   "none",
+	PriorityLow:    "low",
+	PriorityMedium: "medium",
+	PriorityHigh:   "high",
+}
+
+func ParsePriority(s string) (Priority, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	switch name {
+	case "", "none":
+		return PriorityNone, nil
+	case "l":
+		return PriorityLow, nil
+	case "m", "med":
+		return PriorityMedium, nil
+	case "h":
+		return PriorityHigh, nil
+	}
+	for p, n := range priorityNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("invalid priority: %q (must be one of low, medium, high)", s)
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	parsed, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
 type Task struct {
-	ID          int        `json:"id"`
-	Description string     `json:"description"`
-	Completed   bool       `json:"completed"`
-	CreatedAt   time.Time  `json:"created_at"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ID          int         `json:"id"`
+	UID         string      `json:"uid,omitempty"`
+	Project     string      `json:"project,omitempty"`
+	Description string      `json:"description"`
+	Completed   bool        `json:"completed"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	Due         *time.Time  `json:"due,omitempty"`
+	Priority    Priority    `json:"priority,omitempty"`
+	Tags        []string    `json:"tags,omitempty"`
+	Recurrence  *Recurrence `json:"recurrence,omitempty"`
+	SeriesID    int         `json:"series_id,omitempty"`
+	NextID      int         `json:"next_id,omitempty"`
+	ParentID    int         `json:"parent_id,omitempty"`
+	BlockedBy   []int       `json:"blocked_by,omitempty"`
+	TimeEntries []TimeEntry `json:"time_entries,omitempty"`
+	Archived    bool        `json:"archived,omitempty"`
 }
 
 func (t Task) String() string {
@@ -30,14 +101,126 @@ func (t *Task) UnmarshalBinary(data []byte) error {
 	return json.Unmarshal(data, t)
 }
 
+// IsCompletedToday reports whether the task was completed on the current
+// calendar day in the local timezone.
 func (t Task) IsCompletedToday() bool {
+	today := StartOfDay(time.Now())
+	return t.CompletedBetween(today, today.AddDate(0, 0, 1))
+}
+
+// CompletedBetween reports whether the task was completed in [from, to).
+// A zero from or to leaves that end of the range open.
+func (t Task) CompletedBetween(from, to time.Time) bool {
 	if !t.Completed || t.CompletedAt == nil {
 		return false
 	}
+	if !from.IsZero() && t.CompletedAt.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.CompletedAt.Before(to) {
+		return false
+	}
+	return true
+}
 
-	now := time.Now()
-	completedDate := t.CompletedAt.Truncate(24 * time.Hour)
-	todayDate := now.Truncate(24 * time.Hour)
+func (t Task) HasTag(tag string) bool {
+	for _, existing := range t.Tags {
+		if strings.EqualFold(existing, tag) {
+			return true
+		}
+	}
+	return false
+}
 
-	return completedDate.Equal(todayDate)
-}
\ No newline at end of file
+// IsOverdue reports whether the task is still open and its due day has passed.
+// Due dates are day-granular, so a task due today is not yet overdue.
+func (t Task) IsOverdue(now time.Time) bool {
+	return !t.Completed && t.Due != nil && t.Due.Before(StartOfDay(now))
+}
+
+// StartOfDay returns local midnight of the day containing t.
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.In(time.Local).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// NormalizeTags lowercases, trims and de-duplicates tags, dropping empty ones.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(tag, "+")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// TaskFilter selects tasks by their metadata. Zero-valued fields match everything.
+type TaskFilter struct {
+	Tags        []string   `json:"tags,omitempty"`
+	MinPriority Priority   `json:"min_priority,omitempty"`
+	DueBefore   *time.Time `json:"due_before,omitempty"`
+	Overdue     bool       `json:"overdue,omitempty"`
+}
+
+func (f TaskFilter) Match(t Task) bool {
+	for _, tag := range f.Tags {
+		if !t.HasTag(tag) {
+			return false
+		}
+	}
+	if t.Priority < f.MinPriority {
+		return false
+	}
+	if f.DueBefore != nil && (t.Due == nil || !t.Due.Before(*f.DueBefore)) {
+		return false
+	}
+	if f.Overdue && !t.IsOverdue(time.Now()) {
+		return false
+	}
+	return true
+}
+
+func FilterTasks(tasks []Task, filter TaskFilter) []Task {
+	var matched []Task
+	for _, task := range tasks {
+		if filter.Match(task) {
+			matched = append(matched, task)
+		}
+	}
+	return matched
+}
+
+// SortTasks orders tasks in place by the given key: "id", "due", "priority" or "created".
+// Tasks without a due date sort after those that have one.
+func SortTasks(tasks []Task, key string) error {
+	var less func(a, b Task) bool
+
+	switch key {
+	case "", "id":
+		less = func(a, b Task) bool { return a.ID < b.ID }
+	case "created":
+		less = func(a, b Task) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "due":
+		less = func(a, b Task) bool {
+			switch {
+			case a.Due == nil:
+				return false
+			case b.Due == nil:
+				return true
+			}
+			return a.Due.Before(*b.Due)
+		}
+	case "priority":
+		less = func(a, b Task) bool { return a.Priority > b.Priority }
+	default:
+		return fmt.Errorf("invalid sort key: %q (must be one of id, due, priority, created)", key)
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool { return less(tasks[i], tasks[j]) })
+	return nil
+}
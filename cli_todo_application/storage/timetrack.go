@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrNoRunningTask  = errors.New("no task is running")
+	ErrAlreadyRunning = errors.New("task is already running")
+)
+
+// TimeEntry is an interval of work on a task. End is nil while the timer is
+// running.
+type TimeEntry struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Duration returns the length of the entry, counting a running entry up to now.
+func (e TimeEntry) Duration(now time.Time) time.Duration {
+	end := now
+	if e.End != nil {
+		end = *e.End
+	}
+	return end.Sub(e.Start)
+}
+
+// Running returns the task's running time entry, or nil.
+func (t Task) Running() *TimeEntry {
+	for i := range t.TimeEntries {
+		if t.TimeEntries[i].End == nil {
+			return &t.TimeEntries[i]
+		}
+	}
+	return nil
+}
+
+// Tracked returns the total time recorded against the task.
+func (t Task) Tracked(now time.Time) time.Duration {
+	var total time.Duration
+	for _, entry := range t.TimeEntries {
+		total += entry.Duration(now)
+	}
+	return total
+}
+
+// stopTimer ends the task's running time entry, if any, and reports whether
+// there was one.
+func (t *Task) stopTimer(now time.Time) bool {
+	entry := t.Running()
+	if entry == nil {
+		return false
+	}
+	entry.End = &now
+	return true
+}
+
+// StartTask starts the timer on the task with the given ID. Only one task runs
+// at a time, so a timer running on another task is stopped first; that task
+// is returned as stopped.
+func (s *TaskStore) StartTask(id int) (task, stopped *Task, err error) {
+	err = s.update("start", func(m *mutation) error {
+		if task, err = m.get(id); err != nil {
+			return err
+		}
+		if task.Completed {
+			return fmt.Errorf("%w: %d", ErrAlreadyCompleted, id)
+		}
+		if task.Running() != nil {
+			return fmt.Errorf("%w: %d", ErrAlreadyRunning, id)
+		}
+
+		now := time.Now()
+		if stopped, err = m.stopRunning(now); err != nil {
+			return err
+		}
+
+		task.TimeEntries = append(task.TimeEntries, TimeEntry{Start: now})
+		return m.put(task)
+	})
+
+	return task, stopped, err
+}
+
+// StopTask stops the running timer and returns the task it was running on.
+func (s *TaskStore) StopTask() (*Task, error) {
+	var task *Task
+
+	err := s.update("stop", func(m *mutation) error {
+		var err error
+		if task, err = m.stopRunning(time.Now()); err != nil {
+			return err
+		}
+		if task == nil {
+			return ErrNoRunningTask
+		}
+		return nil
+	})
+
+	return task, err
+}
+
+// RunningTask returns the task whose timer is running, or nil.
+func (s *TaskStore) RunningTask() (*Task, error) {
+	tasks, err := s.GetIncompleteTasks()
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		if task.Running() != nil {
+			return &task, nil
+		}
+	}
+	return nil, nil
+}
+
+// stopRunning stops whichever timer is running and returns its task, or nil.
+func (m *mutation) stopRunning(now time.Time) (*Task, error) {
+	all, err := m.all()
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range all {
+		if task.stopTimer(now) {
+			return &task, m.put(&task)
+		}
+	}
+	return nil, nil
+}
+
+// WorkSpan is the time worked on a task during one local day.
+type WorkSpan struct {
+	Day      time.Time
+	Task     Task
+	Duration time.Duration
+}
+
+// WorkSpans splits the time entries of tasks at local midnight and returns the
+// time worked on each task per day within [from, to), ordered by day and task
+// ID. A zero from or to leaves that end of the range open; running entries
+// count up to now.
+func WorkSpans(tasks []Task, from, to, now time.Time) []WorkSpan {
+	type key struct {
+		day time.Time
+		id  int
+	}
+	totals := make(map[key]time.Duration)
+	byID := make(map[int]Task, len(tasks))
+
+	for _, task := range tasks {
+		byID[task.ID] = task
+		for _, entry := range task.TimeEntries {
+			start, end := entry.Start, now
+			if entry.End != nil {
+				end = *entry.End
+			}
+			if !from.IsZero() && start.Before(from) {
+				start = from
+			}
+			if !to.IsZero() && end.After(to) {
+				end = to
+			}
+
+			for start.Before(end) {
+				day := StartOfDay(start)
+				dayEnd := day.AddDate(0, 0, 1)
+				if dayEnd.After(end) {
+					dayEnd = end
+				}
+				totals[key{day, task.ID}] += dayEnd.Sub(start)
+				start = dayEnd
+			}
+		}
+	}
+
+	spans := make([]WorkSpan, 0, len(totals))
+	for k, duration := range totals {
+		spans = append(spans, WorkSpan{Day: k.day, Task: byID[k.id], Duration: duration})
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if !spans[i].Day.Equal(spans[j].Day) {
+			return spans[i].Day.Before(spans[j].Day)
+		}
+		return spans[i].Task.ID < spans[j].Task.ID
+	})
+	return spans
+}
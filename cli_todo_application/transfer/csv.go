@@ -0,0 +1,155 @@
+package transfer
+
+import (
+	"cli_todo_application/storage"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvHeader = []string{"id", "project", "description", "completed", "created_at", "completed_at", "due", "priority", "tags", "recurrence", "archived"}
+
+func encodeCSV(w io.Writer, tasks []storage.Task) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		record := []string{
+			strconv.Itoa(task.ID),
+			task.Project,
+			task.Description,
+			strconv.FormatBool(task.Completed),
+			task.CreatedAt.Format(time.RFC3339),
+			formatTimestamp(task.CompletedAt),
+			formatDate(task.Due),
+			"",
+			strings.Join(task.Tags, ";"),
+			"",
+			strconv.FormatBool(task.Archived),
+		}
+		if task.Priority != storage.PriorityNone {
+			record[7] = task.Priority.String()
+		}
+		if task.Recurrence != nil {
+			record[9] = task.Recurrence.Rule()
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func decodeCSV(r io.Reader) ([]storage.Task, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["description"]; !ok {
+		return nil, fmt.Errorf("CSV is missing a description column")
+	}
+
+	var tasks []storage.Task
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		task, err := csvTask(field)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+func csvTask(field func(string) string) (storage.Task, error) {
+	task := storage.Task{Description: field("description")}
+	if task.Description == "" {
+		return task, fmt.Errorf("description cannot be empty")
+	}
+
+	var err error
+	if value := field("project"); value != "" {
+		if task.Project, err = storage.NormalizeProject(value); err != nil {
+			return task, err
+		}
+	}
+	if value := field("completed"); value != "" {
+		if task.Completed, err = strconv.ParseBool(value); err != nil {
+			return task, fmt.Errorf("invalid completed value: %q", value)
+		}
+	}
+	if value := field("archived"); value != "" {
+		if task.Archived, err = strconv.ParseBool(value); err != nil {
+			return task, fmt.Errorf("invalid archived value: %q", value)
+		}
+	}
+	if value := field("created_at"); value != "" {
+		if task.CreatedAt, err = time.Parse(time.RFC3339, value); err != nil {
+			return task, fmt.Errorf("invalid created_at value: %q", value)
+		}
+	}
+	if value := field("completed_at"); value != "" {
+		completedAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return task, fmt.Errorf("invalid completed_at value: %q", value)
+		}
+		task.CompletedAt = &completedAt
+	}
+	if value := field("due"); value != "" {
+		due, err := parseDate(value)
+		if err != nil {
+			return task, err
+		}
+		task.Due = &due
+	}
+	if task.Priority, err = storage.ParsePriority(field("priority")); err != nil {
+		return task, err
+	}
+	if value := field("tags"); value != "" {
+		task.Tags = strings.Split(value, ";")
+	}
+	if value := field("recurrence"); value != "" {
+		start := task.CreatedAt
+		if task.Due != nil {
+			start = *task.Due
+		}
+		if task.Recurrence, err = storage.ParseRecurrence(value, start); err != nil {
+			return task, err
+		}
+	}
+
+	return task, nil
+}
@@ -0,0 +1,27 @@
+package transfer
+
+import (
+	"cli_todo_application/storage"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSON documents are an array of tasks in the same encoding the store uses.
+
+func encodeJSON(w io.Writer, tasks []storage.Task) error {
+	if tasks == nil {
+		tasks = []storage.Task{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tasks)
+}
+
+func decodeJSON(r io.Reader) ([]storage.Task, error) {
+	var tasks []storage.Task
+	if err := json.NewDecoder(r).Decode(&tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,175 @@
+package transfer
+
+import (
+	"bufio"
+	"cli_todo_application/storage"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// todo.txt lines look like
+//
+//	x 2026-10-18 2026-10-01 (A) Write report +work @writing due:2026-10-20 rec:weekly:mon
+//
+// Priorities map high, medium and low to (A), (B) and (C). The task's project
+// becomes a +project word and its tags become @context words; on import any
+// +project words after the first are kept as tags.
+
+var todoTxtPriorities = map[storage.Priority]string{
+	storage.PriorityHigh:   "(A)",
+	storage.PriorityMedium: "(B)",
+	storage.PriorityLow:    "(C)",
+}
+
+func encodeTodoTxt(w io.Writer, tasks []storage.Task) error {
+	for _, task := range tasks {
+		var parts []string
+
+		if task.Completed {
+			parts = append(parts, "x")
+			if task.CompletedAt != nil {
+				parts = append(parts, task.CompletedAt.Format(dateLayout))
+			}
+		} else if priority, ok := todoTxtPriorities[task.Priority]; ok {
+			parts = append(parts, priority)
+		}
+
+		if !task.CreatedAt.IsZero() {
+			parts = append(parts, task.CreatedAt.Format(dateLayout))
+		}
+
+		parts = append(parts, task.Description)
+		if task.Project != "" {
+			parts = append(parts, "+"+task.Project)
+		}
+		for _, tag := range task.Tags {
+			parts = append(parts, "@"+tag)
+		}
+		if task.Due != nil {
+			parts = append(parts, "due:"+task.Due.Format(dateLayout))
+		}
+		if task.Recurrence != nil {
+			parts = append(parts, "rec:"+task.Recurrence.Rule())
+		}
+		// Completed lines lose their priority marker, so keep it as a tag.
+		if task.Completed && task.Priority != storage.PriorityNone {
+			parts = append(parts, "pri:"+task.Priority.String())
+		}
+
+		if _, err := fmt.Fprintln(w, strings.Join(parts, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeTodoTxt(r io.Reader) ([]storage.Task, error) {
+	var tasks []storage.Task
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		task, err := todoTxtTask(strings.Fields(text))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, scanner.Err()
+}
+
+func todoTxtTask(words []string) (storage.Task, error) {
+	var task storage.Task
+
+	if len(words) > 0 && words[0] == "x" {
+		task.Completed = true
+		words = words[1:]
+		if date, ok := leadingDate(words); ok {
+			task.CompletedAt = &date
+			words = words[1:]
+		}
+	}
+
+	if len(words) > 0 {
+		for priority, marker := range todoTxtPriorities {
+			if words[0] == marker {
+				task.Priority = priority
+				words = words[1:]
+				break
+			}
+		}
+	}
+
+	if date, ok := leadingDate(words); ok {
+		task.CreatedAt = date
+		words = words[1:]
+	}
+
+	var description []string
+	var rule string
+	for _, word := range words {
+		key, value, hasValue := strings.Cut(word, ":")
+		switch {
+		case strings.HasPrefix(word, "+") && len(word) > 1 && task.Project == "":
+			project, err := storage.NormalizeProject(word)
+			if err != nil {
+				return task, err
+			}
+			task.Project = project
+		case strings.HasPrefix(word, "+") && len(word) > 1:
+			task.Tags = append(task.Tags, word[1:])
+		case strings.HasPrefix(word, "@") && len(word) > 1:
+			task.Tags = append(task.Tags, word[1:])
+		case hasValue && key == "due" && value != "":
+			due, err := parseDate(value)
+			if err != nil {
+				return task, err
+			}
+			task.Due = &due
+		case hasValue && key == "rec" && value != "":
+			rule = value
+		case hasValue && key == "pri" && value != "":
+			priority, err := storage.ParsePriority(value)
+			if err != nil {
+				return task, err
+			}
+			task.Priority = priority
+		default:
+			description = append(description, word)
+		}
+	}
+
+	task.Description = strings.Join(description, " ")
+	if task.Description == "" {
+		return task, fmt.Errorf("description cannot be empty")
+	}
+
+	if rule != "" {
+		start := task.CreatedAt
+		if task.Due != nil {
+			start = *task.Due
+		}
+		recurrence, err := storage.ParseRecurrence(rule, start)
+		if err != nil {
+			return task, err
+		}
+		task.Recurrence = recurrence
+	}
+
+	return task, nil
+}
+
+func leadingDate(words []string) (time.Time, bool) {
+	if len(words) == 0 {
+		return time.Time{}, false
+	}
+	date, err := parseDate(words[0])
+	return date, err == nil
+}
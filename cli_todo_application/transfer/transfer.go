@@ -0,0 +1,89 @@
+package transfer
+
+import (
+	"cli_todo_application/storage"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatCSV     Format = "csv"
+	FormatTodoTxt Format = "todotxt"
+)
+
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "todotxt", "todo.txt", "txt":
+		return FormatTodoTxt, nil
+	}
+	return "", fmt.Errorf("unsupported format: %q (must be one of json, csv, todotxt)", name)
+}
+
+// FormatFromPath guesses the format from a file extension, defaulting to JSON.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".txt":
+		return FormatTodoTxt
+	}
+	return FormatJSON
+}
+
+func Encode(w io.Writer, format Format, tasks []storage.Task) error {
+	switch format {
+	case FormatJSON:
+		return encodeJSON(w, tasks)
+	case FormatCSV:
+		return encodeCSV(w, tasks)
+	case FormatTodoTxt:
+		return encodeTodoTxt(w, tasks)
+	}
+	return fmt.Errorf("unsupported format: %q", format)
+}
+
+func Decode(r io.Reader, format Format) ([]storage.Task, error) {
+	switch format {
+	case FormatJSON:
+		return decodeJSON(r)
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatTodoTxt:
+		return decodeTodoTxt(r)
+	}
+	return nil, fmt.Errorf("unsupported format: %q", format)
+}
+
+func parseDate(value string) (time.Time, error) {
+	date, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %q (use YYYY-MM-DD)", value)
+	}
+	return date, nil
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
+
+func formatTimestamp(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
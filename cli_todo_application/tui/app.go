@@ -0,0 +1,505 @@
+package tui
+
+import (
+	"cli_todo_application/storage"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeFilter
+	modeAdd
+	modeEdit
+	modeConfirmDelete
+)
+
+const (
+	reverseVideo = "\x1b[7m"
+	dim          = "\x1b[2m"
+	reset        = "\x1b[0m"
+
+	browseHelp    = "x done  a add  e edit  d delete  c completed  / filter  u undo  r redo  q quit"
+	completedHelp = "d delete  c open tasks  / filter  u undo  r redo  q quit"
+)
+
+// Store is the part of the task store the browser uses. It is implemented by
+// *storage.TaskStore and by the client of a running "task serve".
+type Store interface {
+	AddTask(template storage.Task) (*storage.Task, error)
+	GetProjectTasks(project string) ([]storage.Task, error)
+	GetCompletedTasks(from, to time.Time) ([]storage.Task, error)
+	CompleteTask(id int, force bool) (*storage.Task, error)
+	DeleteTask(id int) (*storage.Task, error)
+	EditTask(id int, edit storage.TaskEdit) (*storage.Task, error)
+	Undo() (*storage.JournalEntry, error)
+	Redo() (*storage.JournalEntry, error)
+}
+
+// app is the state of the full-screen task browser. Every action is written
+// to the store immediately and the list is reloaded from it afterwards.
+type app struct {
+	store   Store
+	project string
+	term    *terminal
+
+	showCompleted bool
+	nodes         []storage.TreeNode
+	cursor        int
+	offset        int
+	filter        string
+
+	mode    mode
+	input   []rune
+	message string
+}
+
+// Run shows the tasks of project in a full-screen terminal interface until the
+// user quits.
+func Run(store Store, project string) error {
+	t, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	a := &app{store: store, project: project, term: t}
+	if err := a.reload(); err != nil {
+		return err
+	}
+
+	for {
+		if err := a.render(); err != nil {
+			return err
+		}
+
+		key, err := t.readKey()
+		if err != nil {
+			return err
+		}
+
+		quit, err := a.handle(key)
+		if err != nil {
+			return err
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// reload fetches the current view from the store, keeping the cursor on the
+// same task when it is still visible.
+func (a *app) reload() error {
+	selected := 0
+	if task, ok := a.selected(); ok {
+		selected = task.ID
+	}
+
+	var nodes []storage.TreeNode
+	if a.showCompleted {
+		tasks, err := a.store.GetCompletedTasks(time.Time{}, time.Time{})
+		if err != nil {
+			return fmt.Errorf("failed to get completed tasks: %w", err)
+		}
+		for i := len(tasks) - 1; i >= 0; i-- {
+			if tasks[i].ProjectName() == a.project {
+				nodes = append(nodes, storage.TreeNode{Task: tasks[i]})
+			}
+		}
+	} else {
+		tasks, err := a.store.GetProjectTasks(a.project)
+		if err != nil {
+			return fmt.Errorf("failed to get tasks: %w", err)
+		}
+		nodes = storage.TaskTree(tasks)
+	}
+
+	a.nodes = nodes[:0:0]
+	for _, node := range nodes {
+		if matchesFilter(node.Task, a.filter) {
+			a.nodes = append(a.nodes, node)
+		}
+	}
+
+	a.cursor = min(a.cursor, max(len(a.nodes)-1, 0))
+	for i, node := range a.nodes {
+		if node.Task.ID == selected {
+			a.cursor = i
+			break
+		}
+	}
+	return nil
+}
+
+func matchesFilter(task storage.Task, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	filter = strings.ToLower(filter)
+	if strings.Contains(strings.ToLower(task.Description), filter) {
+		return true
+	}
+	return slices.ContainsFunc(task.Tags, func(tag string) bool {
+		return strings.Contains(tag, filter)
+	})
+}
+
+func (a *app) selected() (storage.Task, bool) {
+	if a.cursor < 0 || a.cursor >= len(a.nodes) {
+		return storage.Task{}, false
+	}
+	return a.nodes[a.cursor].Task, true
+}
+
+// handle applies a keypress and reports whether the user asked to quit.
+func (a *app) handle(key Key) (bool, error) {
+	if key.Kind == KeyCtrlC {
+		return true, nil
+	}
+
+	switch a.mode {
+	case modeFilter:
+		return false, a.handleFilter(key)
+	case modeAdd, modeEdit:
+		return false, a.handleInput(key)
+	case modeConfirmDelete:
+		return false, a.handleConfirmDelete(key)
+	}
+
+	a.message = ""
+	_, height := a.term.size()
+	page := max(a.listHeight(height)-1, 1)
+
+	switch key.Kind {
+	case KeyUp:
+		a.move(-1)
+	case KeyDown:
+		a.move(1)
+	case KeyPageUp:
+		a.move(-page)
+	case KeyPageDown:
+		a.move(page)
+	case KeyHome:
+		a.move(-len(a.nodes))
+	case KeyEnd:
+		a.move(len(a.nodes))
+	case KeyEnter:
+		return false, a.complete(false)
+	case KeyEscape:
+		if a.filter != "" {
+			a.filter = ""
+			return false, a.reload()
+		}
+	case KeyRune:
+		return a.handleRune(key.Rune)
+	}
+	return false, nil
+}
+
+func (a *app) handleRune(r rune) (bool, error) {
+	switch r {
+	case 'q':
+		return true, nil
+	case 'k':
+		a.move(-1)
+	case 'j':
+		a.move(1)
+	case 'g':
+		a.move(-len(a.nodes))
+	case 'G':
+		a.move(len(a.nodes))
+	case 'x', ' ':
+		return false, a.complete(false)
+	case 'X':
+		return false, a.complete(true)
+	case 'a':
+		if a.showCompleted {
+			a.message = "Switch to open tasks (c) to add a task."
+			return false, nil
+		}
+		a.mode = modeAdd
+		a.input = nil
+	case 'e':
+		if task, ok := a.selected(); ok {
+			a.mode = modeEdit
+			a.input = []rune(task.Description)
+		}
+	case 'd':
+		if _, ok := a.selected(); ok {
+			a.mode = modeConfirmDelete
+		}
+	case 'c':
+		a.showCompleted = !a.showCompleted
+		a.cursor, a.offset = 0, 0
+		return false, a.reload()
+	case '/':
+		a.mode = modeFilter
+		a.input = []rune(a.filter)
+	case 'u':
+		return false, a.undo()
+	case 'r':
+		return false, a.redo()
+	}
+	return false, nil
+}
+
+func (a *app) move(delta int) {
+	a.cursor = max(0, min(a.cursor+delta, len(a.nodes)-1))
+}
+
+func (a *app) handleFilter(key Key) error {
+	switch key.Kind {
+	case KeyEnter:
+		a.mode = modeBrowse
+		return nil
+	case KeyEscape:
+		a.mode = modeBrowse
+		a.input = nil
+	case KeyBackspace:
+		if len(a.input) > 0 {
+			a.input = a.input[:len(a.input)-1]
+		}
+	case KeyRune:
+		a.input = append(a.input, key.Rune)
+	default:
+		return nil
+	}
+
+	a.filter = string(a.input)
+	return a.reload()
+}
+
+func (a *app) handleInput(key Key) error {
+	switch key.Kind {
+	case KeyEscape:
+		a.mode = modeBrowse
+	case KeyBackspace:
+		if len(a.input) > 0 {
+			a.input = a.input[:len(a.input)-1]
+		}
+	case KeyRune:
+		a.input = append(a.input, key.Rune)
+	case KeyEnter:
+		text := strings.TrimSpace(string(a.input))
+		editing := a.mode == modeEdit
+		a.mode = modeBrowse
+		if text == "" {
+			a.message = "Task description cannot be empty."
+			return nil
+		}
+		if editing {
+			return a.edit(text)
+		}
+		return a.add(text)
+	}
+	return nil
+}
+
+func (a *app) handleConfirmDelete(key Key) error {
+	a.mode = modeBrowse
+	if key.Kind != KeyRune || (key.Rune != 'y' && key.Rune != 'Y') {
+		a.message = "Delete cancelled."
+		return nil
+	}
+
+	task, ok := a.selected()
+	if !ok {
+		return nil
+	}
+	if _, err := a.store.DeleteTask(task.ID); err != nil {
+		a.message = "Failed to delete task: " + err.Error()
+		return nil
+	}
+	a.message = fmt.Sprintf("Deleted %q.", task.Description)
+	return a.reload()
+}
+
+func (a *app) complete(force bool) error {
+	task, ok := a.selected()
+	if !ok {
+		return nil
+	}
+	if task.Completed {
+		a.message = "That task is already completed."
+		return nil
+	}
+
+	_, err := a.store.CompleteTask(task.ID, force)
+	if errors.Is(err, storage.ErrOpenSubtasks) {
+		a.message = "That task has open subtasks; press X to complete them too."
+		return nil
+	}
+	if err != nil {
+		a.message = "Failed to complete task: " + err.Error()
+		return nil
+	}
+
+	a.message = fmt.Sprintf("Completed %q.", task.Description)
+	return a.reload()
+}
+
+func (a *app) add(description string) error {
+	task, err := a.store.AddTask(storage.Task{Description: description, Project: a.project})
+	if err != nil {
+		a.message = "Failed to add task: " + err.Error()
+		return nil
+	}
+
+	a.message = fmt.Sprintf("Added %q.", task.Description)
+	if err := a.reload(); err != nil {
+		return err
+	}
+	for i, node := range a.nodes {
+		if node.Task.ID == task.ID {
+			a.cursor = i
+		}
+	}
+	return nil
+}
+
+func (a *app) edit(description string) error {
+	task, ok := a.selected()
+	if !ok {
+		return nil
+	}
+
+	_, err := a.store.EditTask(task.ID, storage.TaskEdit{Description: description})
+	if err != nil {
+		a.message = "Failed to edit task: " + err.Error()
+		return nil
+	}
+
+	a.message = "Task updated."
+	return a.reload()
+}
+
+func (a *app) undo() error {
+	entry, err := a.store.Undo()
+	if errors.Is(err, storage.ErrNothingToUndo) {
+		a.message = "There is nothing to undo."
+		return nil
+	}
+	if err != nil {
+		a.message = "Failed to undo: " + err.Error()
+		return nil
+	}
+
+	a.message = "Undid " + entry.Summary() + "."
+	return a.reload()
+}
+
+func (a *app) redo() error {
+	entry, err := a.store.Redo()
+	if errors.Is(err, storage.ErrNothingToRedo) {
+		a.message = "There is nothing to redo."
+		return nil
+	}
+	if err != nil {
+		a.message = "Failed to redo: " + err.Error()
+		return nil
+	}
+
+	a.message = "Redid " + entry.Summary() + "."
+	return a.reload()
+}
+
+// listHeight is the number of task rows that fit between the header and the
+// two status lines.
+func (a *app) listHeight(height int) int {
+	return max(height-3, 1)
+}
+
+func (a *app) render() error {
+	_, height := a.term.size()
+	rows := a.listHeight(height)
+
+	if a.cursor < a.offset {
+		a.offset = a.cursor
+	}
+	if a.cursor >= a.offset+rows {
+		a.offset = a.cursor - rows + 1
+	}
+
+	view := "open tasks"
+	if a.showCompleted {
+		view = "completed tasks"
+	}
+	header := fmt.Sprintf(" task: %s, %s (%d)", a.project, view, len(a.nodes))
+	if a.filter != "" {
+		header += fmt.Sprintf(", filter %q", a.filter)
+	}
+
+	lines := []string{reverseVideo + header + reset}
+	if len(a.nodes) == 0 {
+		lines = append(lines, dim+"  Nothing to show."+reset)
+	}
+	for i := a.offset; i < len(a.nodes) && i < a.offset+rows; i++ {
+		line := formatNode(a.nodes[i])
+		if i == a.cursor {
+			line = reverseVideo + line + reset
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < rows+1 {
+		lines = append(lines, "")
+	}
+
+	switch a.mode {
+	case modeFilter:
+		lines = append(lines, "Filter: "+string(a.input)+"_", dim+"Enter to keep, Esc to clear"+reset)
+	case modeAdd:
+		lines = append(lines, "New task: "+string(a.input)+"_", dim+"Enter to add, Esc to cancel"+reset)
+	case modeEdit:
+		lines = append(lines, "Edit: "+string(a.input)+"_", dim+"Enter to save, Esc to cancel"+reset)
+	case modeConfirmDelete:
+		task, _ := a.selected()
+		lines = append(lines, fmt.Sprintf("Delete %q? (y/n)", task.Description), "")
+	default:
+		help := browseHelp
+		if a.showCompleted {
+			help = completedHelp
+		}
+		lines = append(lines, a.message, dim+help+reset)
+	}
+
+	return a.term.draw(lines)
+}
+
+func formatNode(node storage.TreeNode) string {
+	task := node.Task
+
+	status := "[ ]"
+	if task.Completed {
+		status = "[x]"
+	}
+
+	var details []string
+	if task.Priority != storage.PriorityNone {
+		details = append(details, "["+task.Priority.String()+"]")
+	}
+	if task.Due != nil {
+		due := "due " + task.Due.Format("2006-01-02")
+		if task.IsOverdue(time.Now()) {
+			due = "OVERDUE, " + due
+		}
+		details = append(details, "("+due+")")
+	}
+	if task.Completed && task.CompletedAt != nil {
+		details = append(details, "(done "+task.CompletedAt.Local().Format("2006-01-02 15:04")+")")
+	}
+	for _, tag := range task.Tags {
+		details = append(details, "+"+tag)
+	}
+
+	line := fmt.Sprintf(" %s%s %d. %s", strings.Repeat("  ", node.Depth), status, task.ID, task.Description)
+	if len(details) > 0 {
+		line += " " + strings.Join(details, " ")
+	}
+	return line
+}
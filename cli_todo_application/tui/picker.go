@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrCancelled is returned by Pick when the user leaves without choosing.
+var ErrCancelled = errors.New("selection cancelled")
+
+// Pick lets the user choose one of items with a fuzzy finder and returns its
+// index. Typing narrows the list to items containing the typed characters in
+// order; the arrow keys move and Enter chooses.
+func Pick(prompt string, items []string) (int, error) {
+	if len(items) == 0 {
+		return -1, fmt.Errorf("nothing to choose from")
+	}
+
+	t, err := openTerminal()
+	if err != nil {
+		return -1, err
+	}
+	defer t.close()
+
+	var query []rune
+	cursor := 0
+	matches := fuzzyFilter(items, "")
+
+	for {
+		_, height := t.size()
+		visible := max(height-2, 1)
+		offset := max(cursor-visible+1, 0)
+
+		lines := []string{fmt.Sprintf("%s> %s%s_%s", prompt, string(query), dim, reset)}
+		for i := offset; i < len(matches) && i < offset+visible; i++ {
+			line := "  " + items[matches[i]]
+			if i == cursor {
+				line = reverseVideo + "> " + items[matches[i]] + reset
+			}
+			lines = append(lines, line)
+		}
+		lines = append(lines, fmt.Sprintf("%s%d/%d  enter choose  esc cancel%s", dim, len(matches), len(items), reset))
+		if err := t.draw(lines); err != nil {
+			return -1, err
+		}
+
+		key, err := t.readKey()
+		if err != nil {
+			return -1, err
+		}
+
+		switch key.Kind {
+		case KeyCtrlC, KeyEscape:
+			return -1, ErrCancelled
+		case KeyEnter:
+			if len(matches) > 0 {
+				return matches[cursor], nil
+			}
+		case KeyUp:
+			cursor = max(cursor-1, 0)
+		case KeyDown:
+			cursor = min(cursor+1, max(len(matches)-1, 0))
+		case KeyBackspace:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				matches, cursor = fuzzyFilter(items, string(query)), 0
+			}
+		case KeyRune:
+			query = append(query, key.Rune)
+			matches, cursor = fuzzyFilter(items, string(query)), 0
+		}
+	}
+}
+
+// fuzzyFilter returns the indexes of the items that match query, best match
+// first and otherwise in their original order.
+func fuzzyFilter(items []string, query string) []int {
+	type match struct {
+		index, score int
+	}
+
+	var matches []match
+	for i, item := range items {
+		if score, ok := fuzzyScore(item, query); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	indexes := make([]int, len(matches))
+	for i, m := range matches {
+		indexes[i] = m.index
+	}
+	return indexes
+}
+
+// fuzzyScore reports whether the characters of query appear in item in order,
+// ignoring case. Matches score higher when their characters are adjacent or
+// start words.
+func fuzzyScore(item, query string) (int, bool) {
+	target := []rune(strings.ToLower(item))
+	score := 0
+	last := -1
+
+	for _, q := range strings.ToLower(query) {
+		found := -1
+		for i := last + 1; i < len(target); i++ {
+			if target[i] == q {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return 0, false
+		}
+
+		switch {
+		case found == last+1 && last >= 0:
+			score += 3
+		case found == 0 || !unicode.IsLetter(target[found-1]) && !unicode.IsDigit(target[found-1]):
+			score += 2
+		default:
+			score -= min(found-last-1, 3)
+		}
+		last = found
+	}
+	return score, true
+}
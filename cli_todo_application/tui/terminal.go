@@ -0,0 +1,198 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Key is a single keypress. Printable characters are reported as KeyRune with
+// Rune set; everything else uses one of the named keys.
+type Key struct {
+	Kind KeyKind
+	Rune rune
+}
+
+type KeyKind int
+
+const (
+	KeyRune KeyKind = iota
+	KeyUp
+	KeyDown
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyEscape
+	KeyBackspace
+	KeyTab
+	KeyCtrlC
+	KeyUnknown
+)
+
+// terminal puts the controlling terminal into raw mode on the alternate
+// screen and restores it on close.
+type terminal struct {
+	in       *os.File
+	out      *bufio.Writer
+	oldState *term.State
+	buf      []byte
+}
+
+func openTerminal() (*terminal, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, fmt.Errorf("standard input and output must be a terminal")
+	}
+
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+	}
+
+	t := &terminal{in: os.Stdin, out: bufio.NewWriter(os.Stdout), oldState: oldState}
+	// Switch to the alternate screen and hide the cursor.
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	return t, t.out.Flush()
+}
+
+func (t *terminal) close() error {
+	t.out.WriteString("\x1b[?25h\x1b[?1049l")
+	t.out.Flush()
+	return term.Restore(int(t.in.Fd()), t.oldState)
+}
+
+// size returns the terminal width and height, with a fallback for terminals
+// that do not report it.
+func (t *terminal) size() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// readKey blocks until a key is pressed.
+func (t *terminal) readKey() (Key, error) {
+	for len(t.buf) == 0 {
+		chunk := make([]byte, 64)
+		n, err := t.in.Read(chunk)
+		if err != nil {
+			return Key{}, err
+		}
+		t.buf = append(t.buf, chunk[:n]...)
+	}
+
+	key, size := decodeKey(t.buf)
+	t.buf = t.buf[size:]
+	return key, nil
+}
+
+// decodeKey parses the first key in b and returns it with the number of bytes
+// it occupied.
+func decodeKey(b []byte) (Key, int) {
+	switch b[0] {
+	case 3:
+		return Key{Kind: KeyCtrlC}, 1
+	case 9:
+		return Key{Kind: KeyTab}, 1
+	case '\r', '\n':
+		return Key{Kind: KeyEnter}, 1
+	case 8, 127:
+		return Key{Kind: KeyBackspace}, 1
+	case 14: // Ctrl-N
+		return Key{Kind: KeyDown}, 1
+	case 16: // Ctrl-P
+		return Key{Kind: KeyUp}, 1
+	case 27:
+		return decodeEscape(b)
+	}
+
+	if b[0] < 32 {
+		return Key{Kind: KeyUnknown}, 1
+	}
+
+	r, size := utf8.DecodeRune(b)
+	return Key{Kind: KeyRune, Rune: r}, size
+}
+
+func decodeEscape(b []byte) (Key, int) {
+	if len(b) < 3 || (b[1] != '[' && b[1] != 'O') {
+		return Key{Kind: KeyEscape}, 1
+	}
+
+	switch b[2] {
+	case 'A':
+		return Key{Kind: KeyUp}, 3
+	case 'B':
+		return Key{Kind: KeyDown}, 3
+	case 'H':
+		return Key{Kind: KeyHome}, 3
+	case 'F':
+		return Key{Kind: KeyEnd}, 3
+	}
+
+	// Sequences such as ESC [ 5 ~ carry a numeric parameter.
+	end := 2
+	for end < len(b) && b[end] >= '0' && b[end] <= '9' {
+		end++
+	}
+	if end < len(b) && b[end] == '~' {
+		switch string(b[2:end]) {
+		case "1", "7":
+			return Key{Kind: KeyHome}, end + 1
+		case "4", "8":
+			return Key{Kind: KeyEnd}, end + 1
+		case "5":
+			return Key{Kind: KeyPageUp}, end + 1
+		case "6":
+			return Key{Kind: KeyPageDown}, end + 1
+		}
+		return Key{Kind: KeyUnknown}, end + 1
+	}
+
+	return Key{Kind: KeyUnknown}, len(b)
+}
+
+// draw replaces the screen contents with lines, truncated to the terminal width.
+func (t *terminal) draw(lines []string) error {
+	width, height := t.size()
+
+	t.out.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i >= height {
+			break
+		}
+		t.out.WriteString(truncate(line, width))
+		if i < len(lines)-1 && i < height-1 {
+			t.out.WriteString("\r\n")
+		}
+	}
+	return t.out.Flush()
+}
+
+// truncate shortens s to at most width visible runes, ignoring ANSI escape
+// sequences when counting.
+func truncate(s string, width int) string {
+	visible := 0
+	inEscape := false
+	for i, r := range s {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+		case inEscape:
+			if r == 'm' {
+				inEscape = false
+			}
+		default:
+			visible++
+			if visible > width {
+				return s[:i] + "\x1b[0m"
+			}
+		}
+	}
+	return s
+}
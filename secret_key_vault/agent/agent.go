@@ -0,0 +1,221 @@
+// Package agent caches unlocked vault keys in a long-running process so that
+// repeated commands do not have to ask for the key again. Clients talk to it
+// over a Unix socket that only the owning user can open.
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SocketEnv names the environment variable that overrides the socket path.
+const SocketEnv = "SECRET_AGENT_SOCK"
+
+// ErrNoKey is returned by Get when the agent holds no key for the vault.
+var ErrNoKey = errors.New("agent has no key for this vault")
+
+type request struct {
+	Op    string `json:"op"`
+	Vault string `json:"vault,omitempty"`
+	Key   string `json:"key,omitempty"`
+}
+
+type response struct {
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// SocketPath returns the agent socket path: $SECRET_AGENT_SOCK, or
+// ~/.secret-agent.sock.
+func SocketPath() (string, error) {
+	if path := os.Getenv(SocketEnv); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".secret-agent.sock"), nil
+}
+
+// Agent holds vault keys in memory, each for at most Timeout after it was
+// stored. A zero Timeout keeps keys until the agent exits.
+type Agent struct {
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	keys map[string]*cachedKey
+}
+
+type cachedKey struct {
+	key   string
+	timer *time.Timer
+}
+
+// Serve starts answering requests on socketPath in the background; close the
+// returned listener to stop. A stale socket left by a crashed agent is
+// replaced, but a live agent is not.
+func (a *Agent) Serve(socketPath string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("an agent is already listening on %s", socketPath)
+	}
+	os.Remove(socketPath)
+
+	listener, err := listen(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go a.handle(conn)
+		}
+	}()
+	return listener, nil
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The socket mode already keeps other users out; this also covers
+	// sockets placed in a shared directory through $SECRET_AGENT_SOCK.
+	if unixConn, ok := conn.(*net.UnixConn); !ok || !fromOwner(unixConn) {
+		json.NewEncoder(conn).Encode(response{Error: "permission denied"})
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(response{Error: "invalid request"})
+		return
+	}
+
+	var resp response
+	switch req.Op {
+	case "get":
+		key, ok := a.get(req.Vault)
+		if !ok {
+			resp.Error = ErrNoKey.Error()
+		}
+		resp.Key = key
+	case "store":
+		a.store(req.Vault, req.Key)
+	case "forget":
+		a.forget()
+	default:
+		resp.Error = fmt.Sprintf("unknown operation %q", req.Op)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// fromOwner reports whether the peer on conn runs as the same user as the
+// agent.
+func fromOwner(conn *net.UnixConn) bool {
+	uid, err := peerUID(conn)
+	return err == nil && uid == os.Getuid()
+}
+
+func (a *Agent) get(vault string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cached, ok := a.keys[vault]
+	if !ok {
+		return "", false
+	}
+	return cached.key, true
+}
+
+func (a *Agent) store(vault, key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.keys == nil {
+		a.keys = make(map[string]*cachedKey)
+	}
+	if old, ok := a.keys[vault]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+
+	cached := &cachedKey{key: key}
+	if a.Timeout > 0 {
+		cached.timer = time.AfterFunc(a.Timeout, func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			if a.keys[vault] == cached {
+				delete(a.keys, vault)
+			}
+		})
+	}
+	a.keys[vault] = cached
+}
+
+func (a *Agent) forget() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, cached := range a.keys {
+		if cached.timer != nil {
+			cached.timer.Stop()
+		}
+	}
+	a.keys = nil
+}
+
+// Get asks the agent on socketPath for the key of the vault at vaultPath.
+func Get(socketPath, vaultPath string) (string, error) {
+	resp, err := call(socketPath, request{Op: "get", Vault: vaultPath})
+	if err != nil {
+		return "", err
+	}
+	return resp.Key, nil
+}
+
+// Store hands the key of the vault at vaultPath to the agent on socketPath.
+func Store(socketPath, vaultPath, key string) error {
+	_, err := call(socketPath, request{Op: "store", Vault: vaultPath, Key: key})
+	return err
+}
+
+// Forget makes the agent on socketPath drop every key it holds.
+func Forget(socketPath string) error {
+	_, err := call(socketPath, request{Op: "forget"})
+	return err
+}
+
+func call(socketPath string, req request) (response, error) {
+	var resp response
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, err
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, err
+	}
+	switch resp.Error {
+	case "":
+		return resp, nil
+	case ErrNoKey.Error():
+		return resp, ErrNoKey
+	}
+	return resp, errors.New(resp.Error)
+}
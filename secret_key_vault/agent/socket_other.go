@@ -0,0 +1,9 @@
+//go:build !unix
+
+package agent
+
+import "net"
+
+func listen(socketPath string) (net.Listener, error) {
+	return net.Listen("unix", socketPath)
+}
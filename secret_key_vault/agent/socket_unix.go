@@ -0,0 +1,16 @@
+//go:build unix
+
+package agent
+
+import (
+	"net"
+	"syscall"
+)
+
+// listen creates the socket with no permissions for group and others from
+// the start, so that no one else can connect before Serve tightens its mode.
+func listen(socketPath string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", socketPath)
+}
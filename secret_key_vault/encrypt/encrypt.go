@@ -1,31 +1,199 @@
 package encrypt
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrDecrypt is returned when a vault cannot be opened with the given key.
+// A wrong key and a damaged or tampered file look the same to AES-GCM.
+var ErrDecrypt = errors.New("wrong key or corrupted vault")
+
+const (
+	// Version 2 encrypts the data directly under a passphrase; version 3
+	// encrypts it under a random data key wrapped for each recipient.
+	singleKeyVersion = 2
+	envelopeVersion  = 3
+
+	kdfScrypt    = "scrypt"
+	cipherAESGCM = "aes-256-gcm"
+
+	keySize  = 32
+	saltSize = 16
+
+	// Default scrypt cost: about 32 MiB of memory per derivation.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	// Upper bound on the cost accepted from a file, so a crafted vault
+	// cannot make us allocate unbounded memory.
+	maxScryptN = 1 << 20
 )
 
+// envelope is the on-disk format of an encrypted vault. The KDF parameters
+// are stored alongside the ciphertext so they can be raised later without
+// breaking existing files.
+type envelope struct {
+	Version    int        `json:"version"`
+	KDF        *kdfParams `json:"kdf,omitempty"`
+	Recipients []stanza   `json:"recipients,omitempty"`
+	Cipher     string     `json:"cipher"`
+	Nonce      []byte     `json:"nonce"`
+	Data       []byte     `json:"data"`
+}
+
+type kdfParams struct {
+	Name string `json:"name"`
+	Salt []byte `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+// Encrypt seals text for a single recipient, the passphrase or identity key.
 func Encrypt(text, key string) (string, error) {
-	block, err := aes.NewCipher([]byte(createHash(key)))
+	keyring, err := NewKeyring(key)
 	if err != nil {
 		return "", err
 	}
-	plainText := []byte(text)
-	ciphertext := make([]byte, aes.BlockSize+len(plainText))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", err
-	}
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plainText)
-	return base64.URLEncoding.EncodeToString(ciphertext), nil
+	return keyring.Seal(text)
 }
 
+// Decrypt opens text produced by Encrypt or Keyring.Seal. Vaults written by
+// older versions are still accepted; see IsLegacy.
 func Decrypt(cryptoText, key string) (string, error) {
-	ciphertext, _ := base64.URLEncoding.DecodeString(cryptoText)
+	text, _, err := Open(cryptoText, key)
+	return text, err
+}
+
+// Open decrypts a vault with key and returns its keyring, which seals new
+// contents for the same recipients. Older single-key vaults get a fresh
+// keyring for key, so sealing with it upgrades them.
+func Open(cryptoText, key string) (string, *Keyring, error) {
+	if IsLegacy(cryptoText) {
+		text, err := decryptLegacy(cryptoText, key)
+		if err != nil {
+			return "", nil, err
+		}
+		keyring, err := NewKeyring(key)
+		return text, keyring, err
+	}
+
+	var env envelope
+	if err := json.Unmarshal([]byte(cryptoText), &env); err != nil {
+		return "", nil, ErrDecrypt
+	}
+	if env.Cipher != cipherAESGCM {
+		return "", nil, fmt.Errorf("unsupported cipher %q", env.Cipher)
+	}
+
+	switch env.Version {
+	case singleKeyVersion:
+		if env.KDF == nil {
+			return "", nil, ErrDecrypt
+		}
+		derived, err := deriveKey(key, *env.KDF)
+		if err != nil {
+			return "", nil, err
+		}
+		text, err := open(derived, env.Nonce, env.Data)
+		if err != nil {
+			return "", nil, err
+		}
+		keyring, err := NewKeyring(key)
+		return string(text), keyring, err
+
+	case envelopeVersion:
+		keyring, err := unwrap(env.Recipients, key)
+		if err != nil {
+			return "", nil, err
+		}
+		text, err := open(keyring.dataKey, env.Nonce, env.Data)
+		if err != nil {
+			return "", nil, err
+		}
+		return string(text), keyring, nil
+	}
+	return "", nil, fmt.Errorf("unsupported vault version %d", env.Version)
+}
+
+// IsLegacy reports whether cryptoText uses the old AES-CFB format, which
+// should be rewritten with Encrypt.
+func IsLegacy(cryptoText string) bool {
+	return !bytes.HasPrefix(bytes.TrimSpace([]byte(cryptoText)), []byte("{"))
+}
+
+func newScryptParams() (kdfParams, error) {
+	params := kdfParams{Name: kdfScrypt, Salt: make([]byte, saltSize), N: scryptN, R: scryptR, P: scryptP}
+	_, err := io.ReadFull(rand.Reader, params.Salt)
+	return params, err
+}
+
+func deriveKey(key string, params kdfParams) ([]byte, error) {
+	if params.Name != kdfScrypt {
+		return nil, fmt.Errorf("unsupported key derivation %q", params.Name)
+	}
+	if params.N < 2 || params.N > maxScryptN || params.N&(params.N-1) != 0 || params.R < 1 || params.P < 1 || params.R*params.P >= 1<<30 {
+		return nil, ErrDecrypt
+	}
+	return scrypt.Key([]byte(key), params.Salt, params.N, params.R, params.P, keySize)
+}
+
+// seal encrypts plain with AES-256-GCM under key and a random nonce.
+func seal(key, plain []byte) (nonce, sealed []byte, err error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plain, nil), nil
+}
+
+// open reverses seal, failing with ErrDecrypt on any mismatch.
+func open(key, nonce, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptLegacy reads the original format: base64 of IV and AES-CFB
+// ciphertext under the zero-padded passphrase. It has no integrity check, so
+// callers must validate the plaintext themselves.
+func decryptLegacy(cryptoText, key string) (string, error) {
+	ciphertext, err := base64.URLEncoding.DecodeString(cryptoText)
+	if err != nil || len(ciphertext) < aes.BlockSize {
+		return "", ErrDecrypt
+	}
 	block, err := aes.NewCipher([]byte(createHash(key)))
 	if err != nil {
 		return "", err
@@ -41,4 +209,4 @@ func createHash(key string) string {
 	hash := make([]byte, 32)
 	copy(hash, key)
 	return string(hash)
-}
\ No newline at end of file
+}
@@ -0,0 +1,403 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// IdentityPrefix starts an X25519 identity (private key). A vault key
+	// with this prefix is used as an identity instead of a passphrase.
+	IdentityPrefix = "VAULT-IDENTITY-"
+	// PublicKeyPrefix starts the public key that goes with an identity.
+	PublicKeyPrefix = "vault-pub-"
+
+	stanzaPassphrase = "scrypt"
+	stanzaX25519     = "x25519"
+
+	// ownerLabel names the recipient of a newly created vault.
+	ownerLabel = "owner"
+
+	x25519Info = "secret_key_vault x25519 v1"
+)
+
+var (
+	ErrRecipientExists   = errors.New("recipient already exists")
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrRemoveSelf        = errors.New("cannot remove the key the vault was opened with (use rekey to change it)")
+	ErrCannotRotate      = errors.New("cannot re-wrap the data key for other passphrase recipients")
+)
+
+// stanza is the data key wrapped for one recipient: under a key derived from
+// a passphrase, or under an X25519 shared secret with a public key.
+type stanza struct {
+	Type      string     `json:"type"`
+	Label     string     `json:"label"`
+	KDF       *kdfParams `json:"kdf,omitempty"`
+	Recipient []byte     `json:"recipient,omitempty"`
+	Ephemeral []byte     `json:"ephemeral,omitempty"`
+	Nonce     []byte     `json:"nonce"`
+	Key       []byte     `json:"key"`
+}
+
+// Keyring is the data key of an opened vault together with the recipients
+// it is wrapped for. Sealing keeps every recipient's stanza, so recipients
+// can share a vault without knowing each other's passphrases.
+type Keyring struct {
+	key     string // the passphrase or identity that opened the vault
+	dataKey []byte
+	stanzas []stanza
+	self    int // index of the stanza that key unwraps
+}
+
+// Recipient describes one of the keys a vault is wrapped for.
+type Recipient struct {
+	Label     string
+	PublicKey string // empty for passphrases
+	Self      bool   // the key the vault was opened with
+}
+
+// NewKeyring creates a fresh data key wrapped for key alone.
+func NewKeyring(key string) (*Keyring, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	st, err := wrapFor(ownerLabel, key, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Keyring{key: key, dataKey: dataKey, stanzas: []stanza{st}}, nil
+}
+
+// Seal encrypts text under the data key for all recipients.
+func (k *Keyring) Seal(text string) (string, error) {
+	nonce, data, err := seal(k.dataKey, []byte(text))
+	if err != nil {
+		return "", err
+	}
+	env := envelope{
+		Version:    envelopeVersion,
+		Recipients: k.stanzas,
+		Cipher:     cipherAESGCM,
+		Nonce:      nonce,
+		Data:       data,
+	}
+	out, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (k *Keyring) Recipients() []Recipient {
+	recipients := make([]Recipient, len(k.stanzas))
+	for i, st := range k.stanzas {
+		recipients[i] = Recipient{Label: st.Label, Self: i == k.self}
+		if st.Type == stanzaX25519 {
+			recipients[i].PublicKey = PublicKeyPrefix + base64.RawURLEncoding.EncodeToString(st.Recipient)
+		}
+	}
+	return recipients
+}
+
+// AddPassphrase wraps the data key for another passphrase.
+func (k *Keyring) AddPassphrase(label, passphrase string) error {
+	if err := k.checkLabel(label); err != nil {
+		return err
+	}
+	st, err := wrapPassphrase(label, passphrase, k.dataKey)
+	if err != nil {
+		return err
+	}
+	k.stanzas = append(k.stanzas, st)
+	return nil
+}
+
+// AddPublicKey wraps the data key for the holder of an X25519 identity.
+func (k *Keyring) AddPublicKey(label, publicKey string) error {
+	if err := k.checkLabel(label); err != nil {
+		return err
+	}
+	pub, err := parsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	st, err := wrapPublicKey(label, pub, k.dataKey)
+	if err != nil {
+		return err
+	}
+	k.stanzas = append(k.stanzas, st)
+	return nil
+}
+
+func (k *Keyring) checkLabel(label string) error {
+	if strings.TrimSpace(label) == "" {
+		return errors.New("recipient label cannot be empty")
+	}
+	for _, st := range k.stanzas {
+		if st.Label == label {
+			return fmt.Errorf("%w: %s", ErrRecipientExists, label)
+		}
+	}
+	return nil
+}
+
+// Remove drops a recipient and then tries to rotate the data key, so the
+// removed recipient cannot read later versions even if they kept the old
+// data key. It reports whether the rotation happened; see Rotate.
+func (k *Keyring) Remove(label string) (rotated bool, err error) {
+	i := k.index(label)
+	if i < 0 {
+		return false, fmt.Errorf("%w: %s", ErrRecipientNotFound, label)
+	}
+	if i == k.self {
+		return false, ErrRemoveSelf
+	}
+	k.stanzas = append(k.stanzas[:i:i], k.stanzas[i+1:]...)
+	if i < k.self {
+		k.self--
+	}
+
+	if err := k.Rotate(); errors.Is(err, ErrCannotRotate) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Rotate replaces the data key with a new one. This is only possible when
+// every other recipient is a public key, since other passphrases are not
+// known here; otherwise it fails with ErrCannotRotate and changes nothing.
+func (k *Keyring) Rotate() error {
+	var blocking []string
+	for i, st := range k.stanzas {
+		if i != k.self && st.Type != stanzaX25519 {
+			blocking = append(blocking, st.Label)
+		}
+	}
+	if len(blocking) > 0 {
+		return fmt.Errorf("%w: %s", ErrCannotRotate, strings.Join(blocking, ", "))
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return err
+	}
+	stanzas := make([]stanza, len(k.stanzas))
+	for i, st := range k.stanzas {
+		var err error
+		if i == k.self {
+			stanzas[i], err = wrapFor(st.Label, k.key, dataKey)
+		} else {
+			pub, perr := ecdh.X25519().NewPublicKey(st.Recipient)
+			if perr != nil {
+				return perr
+			}
+			stanzas[i], err = wrapPublicKey(st.Label, pub, dataKey)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	k.dataKey, k.stanzas = dataKey, stanzas
+	return nil
+}
+
+// Rekey replaces the key the vault was opened with by newKey, keeping its
+// label. The data key is rotated as well when the other recipients allow it;
+// Rekey reports whether it was. If not, anyone who saved the old data key can
+// still read the vault.
+func (k *Keyring) Rekey(newKey string) (rotated bool, err error) {
+	st, err := wrapFor(k.stanzas[k.self].Label, newKey, k.dataKey)
+	if err != nil {
+		return false, err
+	}
+	k.stanzas[k.self] = st
+	k.key = newKey
+
+	if err := k.Rotate(); errors.Is(err, ErrCannotRotate) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (k *Keyring) index(label string) int {
+	for i, st := range k.stanzas {
+		if st.Label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// unwrap finds the stanza that key opens.
+func unwrap(stanzas []stanza, key string) (*Keyring, error) {
+	identity, isIdentity, err := parseIdentity(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, st := range stanzas {
+		var dataKey []byte
+		switch {
+		case isIdentity && st.Type == stanzaX25519 && bytes.Equal(st.Recipient, identity.PublicKey().Bytes()):
+			eph, err := ecdh.X25519().NewPublicKey(st.Ephemeral)
+			if err != nil {
+				return nil, ErrDecrypt
+			}
+			shared, err := identity.ECDH(eph)
+			if err != nil {
+				return nil, ErrDecrypt
+			}
+			wrapKey, err := x25519WrapKey(shared, st.Ephemeral, st.Recipient)
+			if err != nil {
+				return nil, err
+			}
+			dataKey, err = open(wrapKey, st.Nonce, st.Key)
+			if err != nil {
+				return nil, err
+			}
+		case !isIdentity && st.Type == stanzaPassphrase && st.KDF != nil:
+			wrapKey, err := deriveKey(key, *st.KDF)
+			if err != nil {
+				return nil, err
+			}
+			dataKey, err = open(wrapKey, st.Nonce, st.Key)
+			if err != nil {
+				continue // another passphrase's stanza
+			}
+		default:
+			continue
+		}
+
+		if len(dataKey) != keySize {
+			return nil, ErrDecrypt
+		}
+		return &Keyring{key: key, dataKey: dataKey, stanzas: stanzas, self: i}, nil
+	}
+	return nil, ErrDecrypt
+}
+
+// wrapFor wraps dataKey for key, which is a passphrase or an identity.
+func wrapFor(label, key string, dataKey []byte) (stanza, error) {
+	identity, isIdentity, err := parseIdentity(key)
+	if err != nil {
+		return stanza{}, err
+	}
+	if isIdentity {
+		return wrapPublicKey(label, identity.PublicKey(), dataKey)
+	}
+	return wrapPassphrase(label, key, dataKey)
+}
+
+func wrapPassphrase(label, passphrase string, dataKey []byte) (stanza, error) {
+	params, err := newScryptParams()
+	if err != nil {
+		return stanza{}, err
+	}
+	wrapKey, err := deriveKey(passphrase, params)
+	if err != nil {
+		return stanza{}, err
+	}
+	nonce, wrapped, err := seal(wrapKey, dataKey)
+	if err != nil {
+		return stanza{}, err
+	}
+	return stanza{Type: stanzaPassphrase, Label: label, KDF: &params, Nonce: nonce, Key: wrapped}, nil
+}
+
+func wrapPublicKey(label string, pub *ecdh.PublicKey, dataKey []byte) (stanza, error) {
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return stanza{}, err
+	}
+	shared, err := eph.ECDH(pub)
+	if err != nil {
+		return stanza{}, err
+	}
+	ephemeral := eph.PublicKey().Bytes()
+	wrapKey, err := x25519WrapKey(shared, ephemeral, pub.Bytes())
+	if err != nil {
+		return stanza{}, err
+	}
+	nonce, wrapped, err := seal(wrapKey, dataKey)
+	if err != nil {
+		return stanza{}, err
+	}
+	return stanza{
+		Type:      stanzaX25519,
+		Label:     label,
+		Recipient: pub.Bytes(),
+		Ephemeral: ephemeral,
+		Nonce:     nonce,
+		Key:       wrapped,
+	}, nil
+}
+
+// x25519WrapKey derives the key that wraps the data key from an X25519
+// shared secret, bound to both public keys.
+func x25519WrapKey(shared, ephemeral, recipient []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	wrapKey := make([]byte, keySize)
+	_, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(x25519Info)), wrapKey)
+	return wrapKey, err
+}
+
+// GenerateIdentity creates an X25519 identity and returns it with its public
+// key, both in text form.
+func GenerateIdentity() (identity, publicKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return IdentityPrefix + base64.RawURLEncoding.EncodeToString(key.Bytes()),
+		PublicKeyPrefix + base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// parseIdentity decodes key if it is an identity. Any other key is a
+// passphrase.
+func parseIdentity(key string) (*ecdh.PrivateKey, bool, error) {
+	encoded, ok := strings.CutPrefix(key, IdentityPrefix)
+	if !ok {
+		return nil, false, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid identity: %w", err)
+	}
+	identity, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid identity: %w", err)
+	}
+	return identity, true, nil
+}
+
+func parsePublicKey(publicKey string) (*ecdh.PublicKey, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(publicKey), PublicKeyPrefix)
+	if !ok {
+		return nil, fmt.Errorf("invalid public key: must start with %s", PublicKeyPrefix)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return pub, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+
+	"secret_key_vault/secret"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envMapping holds the -map, -all and -prefix flags shared by exec and env.
+type envMapping struct {
+	maps   mapFlag
+	all    bool
+	prefix string
+}
+
+// mapFlag collects repeated -map KEY=ENV_NAME flags.
+type mapFlag map[string]string
+
+func (m mapFlag) String() string {
+	return fmt.Sprint(map[string]string(m))
+}
+
+func (m mapFlag) Set(value string) error {
+	key, name, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New("want KEY=ENV_NAME")
+	}
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	m[key] = name
+	return nil
+}
+
+func parseEnvMapping(name string, args []string) (envMapping, []string) {
+	mapping := envMapping{maps: mapFlag{}}
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Var(mapping.maps, "map", "Export secret KEY as ENV_NAME (KEY=ENV_NAME, repeatable)")
+	flags.BoolVar(&mapping.all, "all", false, "Export every secret whose key starts with -prefix")
+	flags.StringVar(&mapping.prefix, "prefix", "", "With -all, only export keys with this prefix, which is dropped from the names")
+	flags.Parse(args)
+
+	if len(mapping.maps) == 0 && !mapping.all {
+		log.Fatalf("Nothing to export: use -map KEY=ENV_NAME or -all")
+	}
+	return mapping, flags.Args()
+}
+
+// environment returns the selected secrets as NAME=value pairs sorted by name.
+// With -all, a key becomes a name by dropping the prefix, upper-casing it and
+// replacing other characters with underscores, so "aws/secret-key" with
+// prefix "aws/" becomes SECRET_KEY. Binary values stored with set-file are
+// left out, since the environment cannot hold them.
+func (m envMapping) environment(vault *secret.FileVault) ([]string, error) {
+	secrets, err := vault.List("")
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]secret.Entry, len(secrets))
+	for _, s := range secrets {
+		entries[s.Key] = s.Entry
+	}
+
+	env := make(map[string]string)
+	if m.all {
+		for _, s := range secrets {
+			rest, ok := strings.CutPrefix(s.Key, m.prefix)
+			if !ok || s.Binary {
+				continue
+			}
+			name := envName(rest)
+			if !envNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("key %q does not make a valid environment variable name; use -map", s.Key)
+			}
+			env[name] = string(s.Value)
+		}
+	}
+	for key, name := range m.maps {
+		entry, ok := entries[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", secret.ErrNotFound, key)
+		}
+		if entry.Binary {
+			return nil, fmt.Errorf("%w: %s", secret.ErrBinary, key)
+		}
+		env[name] = string(entry.Value)
+	}
+
+	pairs := make([]string, 0, len(env))
+	for name, value := range env {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return pairs, nil
+}
+
+func envName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// runExec starts command with the selected secrets added to its environment
+// and exits with its exit status. The secrets are never written anywhere
+// else, and the vault key itself is not passed on.
+func runExec(vault *secret.FileVault, args []string) {
+	mapping, command := parseEnvMapping("exec", args)
+	if len(command) == 0 {
+		log.Fatal("No command given. Usage: secret exec [-map KEY=ENV_NAME]... [-all [-prefix p]] -- command [args...]")
+	}
+
+	pairs, err := mapping.environment(vault)
+	if err != nil {
+		log.Fatalf("Failed to read secrets: %v", err)
+	}
+
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, keyEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(env, pairs...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// The child gets terminal signals itself; keep them from killing us
+	// before it has exited.
+	signal.Notify(make(chan os.Signal, 1), os.Interrupt)
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(max(exitErr.ExitCode(), 1)) // -1 when killed by a signal
+	}
+	if err != nil {
+		log.Fatalf("Failed to run command: %v", err)
+	}
+}
+
+// runEnv prints the selected secrets as export lines for POSIX shells, as in
+// eval "$(secret env -all -prefix aws/)".
+func runEnv(vault *secret.FileVault, args []string) {
+	mapping, rest := parseEnvMapping("env", args)
+	requireArgs(rest, 0, 0)
+
+	pairs, err := mapping.environment(vault)
+	if err != nil {
+		log.Fatalf("Failed to read secrets: %v", err)
+	}
+	for _, kv := range pairs {
+		name, value, _ := strings.Cut(kv, "=")
+		fmt.Printf("export %s=%s\n", name, shellQuote(value))
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -1,3 +1,9 @@
 module secret_key_vault
 
 go 1.23.10
+
+require (
+	golang.org/x/crypto v0.40.0
+	golang.org/x/sys v0.34.0
+	golang.org/x/term v0.33.0
+)
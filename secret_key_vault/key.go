@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"secret_key_vault/agent"
+	"secret_key_vault/secret"
+)
+
+// keyEnv names the environment variable that can hold the vault key.
+const keyEnv = "SECRET_KEY"
+
+// keySources holds the flags that say where to read the vault key from.
+type keySources struct {
+	key     string // -k, kept for compatibility; visible in ps and history
+	keyFD   int
+	keyFile string
+}
+
+// vaultKey finds the key for the vault at path. It uses, in order: -k,
+// -key-fd, -key-file, $SECRET_KEY, a running agent, and finally a no-echo
+// prompt on the terminal. A prompted key is handed to the agent, if one is
+// running, once it has been checked against the vault.
+func vaultKey(path string, src keySources) (string, error) {
+	switch {
+	case src.key != "":
+		return src.key, nil
+	case src.keyFD >= 0:
+		return readKey(os.NewFile(uintptr(src.keyFD), "key-fd"))
+	case src.keyFile != "":
+		return readKeyFile(src.keyFile)
+	case os.Getenv(keyEnv) != "":
+		return os.Getenv(keyEnv), nil
+	}
+
+	socket, socketErr := agent.SocketPath()
+	if socketErr == nil {
+		if key, err := agent.Get(socket, path); err == nil {
+			return key, nil
+		}
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("encryption key required: use -key-fd, -key-file, $" + keyEnv + " or run interactively")
+	}
+
+	vault := secret.NewFileVault("", path)
+	key, err := prompt("Vault key: ")
+	if err != nil {
+		return "", err
+	}
+	if vault.IsNew() {
+		again, err := prompt("Repeat key for the new vault: ")
+		if err != nil {
+			return "", err
+		}
+		if again != key {
+			return "", errors.New("keys do not match")
+		}
+	}
+
+	vault.Key = key
+	if err := vault.Verify(); err != nil {
+		return "", err
+	}
+	if socketErr == nil {
+		agent.Store(socket, path, key) // caching is best effort
+	}
+	return key, nil
+}
+
+func prompt(text string) (string, error) {
+	fmt.Fprint(os.Stderr, text)
+	key, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(key) == 0 {
+		return "", errors.New("empty key")
+	}
+	return string(key), nil
+}
+
+// readKey reads a key from r, dropping one trailing newline.
+func readKey(r io.ReadCloser) (string, error) {
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("cannot read key: %w", err)
+	}
+	key := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if key == "" {
+		return "", errors.New("empty key")
+	}
+	return key, nil
+}
+
+func readKeyFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read key: %w", err)
+	}
+	if info, err := f.Stat(); err == nil && info.Mode().Perm()&0077 != 0 {
+		log.Printf("Warning: key file %s is accessible by other users", path)
+	}
+	return readKey(f)
+}
+
+// newKey reads a further key, such as the replacement key for rekey or a
+// teammate's passphrase: from file when one is given, otherwise from a
+// prompt that asks twice.
+func newKey(file, what string) (string, error) {
+	if file != "" {
+		return readKeyFile(file)
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("%s required: pass a file or run interactively", what)
+	}
+	key, err := prompt(strings.ToUpper(what[:1]) + what[1:] + ": ")
+	if err != nil {
+		return "", err
+	}
+	again, err := prompt("Repeat " + what + ": ")
+	if err != nil {
+		return "", err
+	}
+	if again != key {
+		return "", errors.New("keys do not match")
+	}
+	return key, nil
+}
@@ -5,10 +5,74 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"secret_key_vault/agent"
 	"secret_key_vault/secret"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
+const usage = `Usage: secret [key options] command [arguments]
+
+The vault key is read from the first of these that is given:
+  -key-fd N                  read it from file descriptor N
+  -key-file path             read it from a file (keep it private)
+  $SECRET_KEY                the environment variable
+  a running agent            see "secret agent"
+  a no-echo prompt           when stdin is a terminal
+The old -k key flag still works but exposes the key in ps and shell history.
+
+Commands:
+  get key                    print the value of key
+  set [meta options] key [value]
+                             store value under key
+  get-file [-o file] key     write a file value (or any value) to a file or
+                             to stdout
+  set-file [meta options] key file
+                             store the contents of a file, which may be binary
+  list [-values] [-l] [-expiring 30d] [pattern]
+                             list keys, optionally only those matching a glob
+                             pattern such as "aws/*"; -values shows values,
+                             -l metadata, and -expiring only the secrets that
+                             expire within the given time or have expired
+  delete key                 remove key
+  rename old new             rename a key; fails if new already exists
+  exists key                 exit with status 0 if key exists and 1 if not
+  restore [-list] [N]        roll the vault back to backup N (default 1, the
+                             version before the last change); -list shows
+                             the backups kept next to the vault
+  exec [-map KEY=ENV]... [-all [-prefix p]] -- command [args...]
+                             run command with secrets in its environment;
+                             -all exports every key starting with the prefix,
+                             named by dropping the prefix and upper-casing
+  env [-map KEY=ENV]... [-all [-prefix p]]
+                             print export lines for eval in a shell
+  rekey [-new-key-file f]    re-encrypt the vault under a new key; other
+                             recipients keep their access
+  recipients                 list the passphrases and public keys that can
+                             open the vault
+  recipients add [-public key | -passphrase-file f] label
+                             share the vault with another passphrase or the
+                             holder of an identity
+  recipients remove label    revoke a recipient
+  keygen -o file             create an identity to use as a vault key with
+                             -key-file, and print its public key
+  agent [-timeout 15m]       cache keys entered at the prompt for the given
+                             time, listening on ~/.secret-agent.sock
+                             (or $SECRET_AGENT_SOCK) until interrupted
+  lock                       make the agent forget every cached key
+
+Meta options for set and set-file: -desc text, -tag t (repeatable), and
+-expires 2006-01-02 or 90d ("never" clears it). Options that are not given
+keep their current values. Options go before the key; use -- before a key
+that starts with "-".
+
+get and set ignore arguments after the key and value. The other commands
+exit with this text when given more or fewer arguments than shown.`
+
 func getSecretsFilePath() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -17,46 +81,288 @@ func getSecretsFilePath() string {
 	return filepath.Join(home, ".secrets.enc")
 }
 
+// anyMore lets requireArgs accept any number of extra arguments. get and set
+// ignored them before the other commands existed, and scripts rely on that.
+const anyMore = -1
+
+// requireArgs exits with the usage text unless args has between min and max
+// entries.
+func requireArgs(args []string, min, max int) {
+	if len(args) < min || (max != anyMore && len(args) > max) {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
 func main() {
-	var encodingKey string
-	flag.StringVar(&encodingKey, "k", "", "Encryption key")
+	var keys keySources
+	flag.StringVar(&keys.key, "k", "", "Encryption key (visible to other users; prefer the options below)")
+	flag.IntVar(&keys.keyFD, "key-fd", -1, "Read the encryption key from this file descriptor")
+	flag.StringVar(&keys.keyFile, "key-file", "", "Read the encryption key from this file")
+	flag.Usage = func() { fmt.Fprintln(flag.CommandLine.Output(), usage) }
 	flag.Parse()
 
 	args := flag.Args() // non-flag arguments
-	
-	if len(args) < 2 {
-		fmt.Println("Usage: secret [get|set] key [value] -k encryption-key")
+
+	if len(args) < 1 {
+		fmt.Println(usage)
 		return
 	}
 
 	operation := args[0]
-	key := args[1]
-	value := ""
-	
-	if len(args) > 2 && operation == "set" {
-		value = args[2]
+	args = args[1:]
+
+	switch operation {
+	case "agent":
+		runAgent(args)
+		return
+	case "lock":
+		requireArgs(args, 0, 0)
+		socket, err := agent.SocketPath()
+		if err == nil {
+			err = agent.Forget(socket)
+		}
+		if err != nil {
+			log.Fatalf("Failed to reach agent: %v", err)
+		}
+		fmt.Println("Agent locked!")
+		return
+	case "keygen":
+		runKeygen(args)
+		return
+	case "get", "set", "get-file", "set-file", "list", "delete", "rename", "exists", "restore", "exec", "env",
+		"rekey", "recipients":
+	default:
+		fmt.Println("Unknown command.")
+		fmt.Println(usage)
+		return
 	}
 
-	if encodingKey == "" {
-		log.Fatal("Encryption key required. Use -k flag.")
+	path := getSecretsFilePath()
+	encodingKey, err := vaultKey(path, keys)
+	if err != nil {
+		log.Fatalf("Failed to get encryption key: %v", err)
 	}
 
-	vault := secret.NewFileVault(encodingKey, getSecretsFilePath())
+	vault := secret.NewFileVault(encodingKey, path)
 
 	switch operation {
 	case "set":
-		err := vault.Set(key, value)
+		setFlags := flag.NewFlagSet("set", flag.ExitOnError)
+		var meta metaFlags
+		meta.register(setFlags)
+		setFlags.Parse(args)
+		args = setFlags.Args()
+		requireArgs(args, 1, anyMore)
+		value := ""
+		if len(args) > 1 {
+			value = args[1]
+		}
+		changes, err := meta.meta(setFlags)
 		if err != nil {
+			log.Fatal(err)
+		}
+		if err := vault.Put(args[0], []byte(value), false, changes); err != nil {
 			log.Fatalf("Failed to set key: %v", err)
 		}
 		fmt.Println("Value set!")
+	case "set-file":
+		setFlags := flag.NewFlagSet("set-file", flag.ExitOnError)
+		var meta metaFlags
+		meta.register(setFlags)
+		setFlags.Parse(args)
+		args = setFlags.Args()
+		requireArgs(args, 2, 2)
+		changes, err := meta.meta(setFlags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := vault.SetFile(args[0], args[1], changes); err != nil {
+			log.Fatalf("Failed to set key: %v", err)
+		}
+		fmt.Println("File stored!")
 	case "get":
-		val, err := vault.Get(key)
+		requireArgs(args, 1, anyMore)
+		entry, err := vault.Entry(args[0])
+		if err == nil && entry.Binary {
+			err = secret.ErrBinary
+		}
 		if err != nil {
 			log.Fatalf("Failed to get key: %v", err)
 		}
-		fmt.Printf("%s\n", val)
-	default:
-		fmt.Println("Unknown command. Use get or set.")
+		warnExpired(args[0], entry)
+		fmt.Printf("%s\n", entry.Value)
+	case "get-file":
+		getFlags := flag.NewFlagSet("get-file", flag.ExitOnError)
+		out := getFlags.String("o", "", "Write the value to this file instead of stdout")
+		getFlags.Parse(args)
+		args = getFlags.Args()
+		requireArgs(args, 1, 1)
+		entry, err := vault.Entry(args[0])
+		if err != nil {
+			log.Fatalf("Failed to get key: %v", err)
+		}
+		warnExpired(args[0], entry)
+		if *out == "" {
+			os.Stdout.Write(entry.Value)
+		} else if err := os.WriteFile(*out, entry.Value, 0600); err != nil {
+			log.Fatalf("Failed to write file: %v", err)
+		}
+	case "list":
+		listFlags := flag.NewFlagSet("list", flag.ExitOnError)
+		showValues := listFlags.Bool("values", false, "Show values as well as keys")
+		long := listFlags.Bool("l", false, "Show descriptions, tags, update times and expiry")
+		expiring := listFlags.String("expiring", "", "Only show secrets that expire within this time (e.g. 30d) or have expired")
+		listFlags.Parse(args)
+		args = listFlags.Args()
+		requireArgs(args, 0, 1)
+		pattern := ""
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+
+		var within time.Duration
+		if *expiring != "" {
+			var err error
+			if within, err = parseAge(*expiring); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		secrets, err := vault.List(pattern)
+		if err != nil {
+			log.Fatalf("Failed to list keys: %v", err)
+		}
+		now := time.Now()
+		for _, s := range secrets {
+			if *expiring != "" && !s.ExpiresWithin(within, now) {
+				continue
+			}
+			printSecret(s, *showValues, *long || *expiring != "", now)
+		}
+	case "delete":
+		requireArgs(args, 1, 1)
+		if err := vault.Delete(args[0]); err != nil {
+			log.Fatalf("Failed to delete key: %v", err)
+		}
+		fmt.Println("Key deleted!")
+	case "rename":
+		requireArgs(args, 2, 2)
+		if err := vault.Rename(args[0], args[1]); err != nil {
+			log.Fatalf("Failed to rename key: %v", err)
+		}
+		fmt.Println("Key renamed!")
+	case "exists":
+		requireArgs(args, 1, 1)
+		ok, err := vault.Exists(args[0])
+		if err != nil {
+			log.Fatalf("Failed to check key: %v", err)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	case "restore":
+		runRestore(vault, args)
+	case "rekey":
+		runRekey(vault, args)
+	case "recipients":
+		runRecipients(vault, args)
+	case "exec":
+		runExec(vault, args)
+	case "env":
+		runEnv(vault, args)
+	}
+}
+
+func runRestore(vault *secret.FileVault, args []string) {
+	restoreFlags := flag.NewFlagSet("restore", flag.ExitOnError)
+	list := restoreFlags.Bool("list", false, "List the backups instead of restoring one")
+	restoreFlags.Parse(args)
+	requireArgs(restoreFlags.Args(), 0, 1)
+
+	if *list {
+		backups, err := vault.ListBackups()
+		if err != nil {
+			log.Fatalf("Failed to list backups: %v", err)
+		}
+		for _, b := range backups {
+			fmt.Printf("%d\t%s\n", b.N, b.ModTime.Format("2006-01-02 15:04:05"))
+		}
+		return
+	}
+
+	n := 1
+	if restoreFlags.NArg() == 1 {
+		var err error
+		if n, err = strconv.Atoi(restoreFlags.Arg(0)); err != nil || n < 1 {
+			log.Fatalf("Invalid backup number: %s", restoreFlags.Arg(0))
+		}
+	}
+	if err := vault.Restore(n); err != nil {
+		log.Fatalf("Failed to restore backup: %v", err)
 	}
-}
\ No newline at end of file
+	fmt.Printf("Restored backup %d! The replaced version is now backup 1.\n", n)
+}
+
+func printSecret(s secret.Secret, showValue, long bool, now time.Time) {
+	line := s.Key
+	if showValue {
+		if s.Binary {
+			line += fmt.Sprintf("=<binary, %d bytes>", len(s.Value))
+		} else {
+			line += "=" + string(s.Value)
+		}
+	}
+	if long {
+		var details []string
+		if s.Description != "" {
+			details = append(details, s.Description)
+		}
+		for _, tag := range s.Tags {
+			details = append(details, "#"+tag)
+		}
+		if !s.Updated.IsZero() {
+			details = append(details, "updated "+s.Updated.Local().Format(dateLayout))
+		}
+		if expiry := describeExpiry(s.Entry, now); expiry != "" {
+			details = append(details, expiry)
+		}
+		if len(details) > 0 {
+			line += "\t" + strings.Join(details, "  ")
+		}
+	} else if s.Expired(now) {
+		line += "\t(expired)"
+	}
+	fmt.Println(line)
+}
+
+// warnExpired tells the user on stderr that a secret they read is due for
+// rotation.
+func warnExpired(key string, entry secret.Entry) {
+	if entry.Expired(time.Now()) {
+		log.Printf("Warning: %s expired on %s", key, entry.Expires.Local().Format(dateLayout))
+	}
+}
+
+// runAgent serves the key cache until the process is interrupted.
+func runAgent(args []string) {
+	agentFlags := flag.NewFlagSet("agent", flag.ExitOnError)
+	timeout := agentFlags.Duration("timeout", 15*time.Minute, "Forget each key this long after it was entered (0 keeps it)")
+	agentFlags.Parse(args)
+	requireArgs(agentFlags.Args(), 0, 0)
+
+	socket, err := agent.SocketPath()
+	if err != nil {
+		log.Fatalf("Failed to find agent socket: %v", err)
+	}
+	listener, err := (&agent.Agent{Timeout: *timeout}).Serve(socket)
+	if err != nil {
+		log.Fatalf("Failed to start agent: %v", err)
+	}
+	fmt.Printf("Agent listening on %s\n", socket)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	listener.Close()
+}
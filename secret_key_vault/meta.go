@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"secret_key_vault/secret"
+)
+
+const dateLayout = "2006-01-02"
+
+// metaFlags holds the metadata flags of set and set-file.
+type metaFlags struct {
+	description string
+	tags        tagFlag
+	expires     string
+	set         map[string]bool
+}
+
+// tagFlag collects repeated or comma-separated -tag flags.
+type tagFlag []string
+
+func (t *tagFlag) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagFlag) Set(value string) error {
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+func (m *metaFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&m.description, "desc", "", "Describe the secret")
+	fs.Var(&m.tags, "tag", "Tag the secret (repeatable or comma-separated; replaces existing tags)")
+	fs.StringVar(&m.expires, "expires", "", "Expiry as a date (2006-01-02) or age from now (90d, 12w); \"never\" clears it")
+}
+
+// meta returns the metadata changes for the flags that were given.
+func (m *metaFlags) meta(fs *flag.FlagSet) (secret.Meta, error) {
+	var meta secret.Meta
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "desc":
+			meta.Description = &m.description
+		case "tag":
+			meta.Tags = append([]string{}, m.tags...)
+		case "expires":
+			if m.expires == "never" {
+				meta.ClearExpiry = true
+				return
+			}
+			var expires time.Time
+			if expires, err = parseExpiry(m.expires, time.Now()); err == nil {
+				meta.Expires = &expires
+			}
+		}
+	})
+	return meta, err
+}
+
+// parseExpiry accepts a date, taken as the start of that day in local time,
+// or an age from now such as "90d".
+func parseExpiry(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation(dateLayout, s, time.Local); err == nil {
+		return t.UTC(), nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry %q: use a date (%s) or an age such as 90d", s, dateLayout)
+	}
+	return now.Add(age).UTC(), nil
+}
+
+// parseAge parses a number of days ("30d") or weeks ("2w"), or a Go duration.
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// describeExpiry renders an entry's expiry for listings.
+func describeExpiry(e secret.Entry, now time.Time) string {
+	switch {
+	case e.Expires == nil:
+		return ""
+	case e.Expired(now):
+		return "EXPIRED " + e.Expires.Local().Format(dateLayout)
+	}
+	days := int(math.Ceil(e.Expires.Sub(now).Hours() / 24))
+	return fmt.Sprintf("expires %s (in %d days)", e.Expires.Local().Format(dateLayout), days)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"secret_key_vault/agent"
+	"secret_key_vault/encrypt"
+	"secret_key_vault/secret"
+)
+
+func runRekey(vault *secret.FileVault, args []string) {
+	rekeyFlags := flag.NewFlagSet("rekey", flag.ExitOnError)
+	keyFile := rekeyFlags.String("new-key-file", "", "Read the new key (passphrase or identity) from this file")
+	rekeyFlags.Parse(args)
+	requireArgs(rekeyFlags.Args(), 0, 0)
+
+	key, err := newKey(*keyFile, "new key")
+	if err != nil {
+		log.Fatalf("Failed to get new key: %v", err)
+	}
+	oldKey := vault.Key
+	rotated, err := vault.Rekey(key)
+	if err != nil {
+		log.Fatalf("Failed to rekey vault: %v", err)
+	}
+
+	// Keep a running agent in step, but only if it held the old key.
+	if socket, err := agent.SocketPath(); err == nil {
+		if cached, err := agent.Get(socket, vault.Path); err == nil && cached == oldKey {
+			agent.Store(socket, vault.Path, key)
+		}
+	}
+	fmt.Println("Vault rekeyed!")
+	if !rotated {
+		fmt.Println("The data key was not rotated, since it cannot be re-wrapped for the other")
+		fmt.Printf("passphrase recipients (%s). Anyone who saved it from the old key could\n", strings.Join(rotationBlockers(vault), ", "))
+		fmt.Println("still read new versions.")
+	}
+}
+
+// rotationBlockers lists the passphrase recipients other than the current key,
+// whose passphrases are needed to re-wrap a new data key.
+func rotationBlockers(vault *secret.FileVault) []string {
+	recipients, err := vault.Recipients()
+	if err != nil {
+		log.Fatalf("Failed to list recipients: %v", err)
+	}
+	var labels []string
+	for _, r := range recipients {
+		if !r.Self && r.PublicKey == "" {
+			labels = append(labels, r.Label)
+		}
+	}
+	return labels
+}
+
+func runRecipients(vault *secret.FileVault, args []string) {
+	if len(args) == 0 {
+		recipients, err := vault.Recipients()
+		if err != nil {
+			log.Fatalf("Failed to list recipients: %v", err)
+		}
+		for _, r := range recipients {
+			line := r.Label
+			if r.PublicKey != "" {
+				line += "\t" + r.PublicKey
+			} else {
+				line += "\tpassphrase"
+			}
+			if r.Self {
+				line += "\t(you)"
+			}
+			fmt.Println(line)
+		}
+		return
+	}
+
+	switch args[0] {
+	case "add":
+		addFlags := flag.NewFlagSet("recipients add", flag.ExitOnError)
+		publicKey := addFlags.String("public", "", "Add this public key (from secret keygen) instead of a passphrase")
+		passphraseFile := addFlags.String("passphrase-file", "", "Read the new passphrase from this file")
+		addFlags.Parse(args[1:])
+		requireArgs(addFlags.Args(), 1, 1)
+		label := addFlags.Arg(0)
+
+		if *publicKey != "" {
+			err := vault.AddPublicKey(label, *publicKey)
+			if err != nil {
+				log.Fatalf("Failed to add recipient: %v", err)
+			}
+		} else {
+			passphrase, err := newKey(*passphraseFile, "passphrase for "+label)
+			if err != nil {
+				log.Fatalf("Failed to get passphrase: %v", err)
+			}
+			if err := vault.AddPassphrase(label, passphrase); err != nil {
+				log.Fatalf("Failed to add recipient: %v", err)
+			}
+		}
+		fmt.Println("Recipient added!")
+	case "remove":
+		requireArgs(args[1:], 1, 1)
+		rotated, err := vault.RemoveRecipient(args[1])
+		if err != nil {
+			log.Fatalf("Failed to remove recipient: %v", err)
+		}
+		fmt.Println("Recipient removed!")
+		if !rotated {
+			fmt.Println("The data key was not rotated, since it cannot be re-wrapped for the other")
+			fmt.Printf("passphrase recipients (%s). The removed recipient could still read new\n", strings.Join(rotationBlockers(vault), ", "))
+			fmt.Println("versions if they saved the data key.")
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+// runKeygen writes a new identity to a private file and prints its public
+// key, which others add with "secret recipients add -public".
+func runKeygen(args []string) {
+	keygenFlags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := keygenFlags.String("o", "", "Write the identity to this file (required; it must not exist)")
+	keygenFlags.Parse(args)
+	requireArgs(keygenFlags.Args(), 0, 0)
+	if *out == "" {
+		log.Fatal("Output file required. Use -o flag.")
+	}
+
+	identity, publicKey, err := encrypt.GenerateIdentity()
+	if err != nil {
+		log.Fatalf("Failed to generate identity: %v", err)
+	}
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Fatalf("Failed to write identity: %v", err)
+	}
+	if _, err := fmt.Fprintln(f, identity); err != nil {
+		log.Fatalf("Failed to write identity: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write identity: %v", err)
+	}
+	fmt.Println(publicKey)
+}
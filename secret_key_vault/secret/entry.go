@@ -0,0 +1,107 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// dataFormat versions the decrypted contents of a vault. Vaults written
+// before entries had metadata hold a plain map of keys to string values.
+const dataFormat = 2
+
+// Entry is a stored secret with its metadata. Entries from old vaults have
+// zero Created and Updated times.
+type Entry struct {
+	Value       []byte     `json:"value"`
+	Binary      bool       `json:"binary,omitempty"` // set by SetFile
+	Description string     `json:"description,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Created     time.Time  `json:"created"`
+	Updated     time.Time  `json:"updated"`
+	Expires     *time.Time `json:"expires,omitempty"`
+}
+
+// Meta changes the metadata of an entry when it is set. Nil fields keep the
+// current values.
+type Meta struct {
+	Description *string
+	Tags        []string
+	Expires     *time.Time
+	// ClearExpiry removes the expiry date.
+	ClearExpiry bool
+}
+
+// Expired reports whether the entry's expiry date has passed at now.
+func (e Entry) Expired(now time.Time) bool {
+	return e.Expires != nil && !now.Before(*e.Expires)
+}
+
+// ExpiresWithin reports whether the entry expires before now+d, including
+// entries that have already expired.
+func (e Entry) ExpiresWithin(d time.Duration, now time.Time) bool {
+	return e.Expires != nil && e.Expires.Before(now.Add(d))
+}
+
+// HasTag reports whether the entry carries tag.
+func (e Entry) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, tag)
+}
+
+func (m Meta) apply(e *Entry) {
+	if m.Description != nil {
+		e.Description = *m.Description
+	}
+	if m.Tags != nil {
+		e.Tags = m.Tags
+	}
+	if m.Expires != nil {
+		e.Expires = m.Expires
+	}
+	if m.ClearExpiry {
+		e.Expires = nil
+	}
+}
+
+type vaultData struct {
+	Format  int              `json:"format"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+func decodeEntries(plain []byte) (map[string]Entry, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(plain, &raw); err != nil {
+		return nil, err
+	}
+
+	// A legacy secret named "format" holds a string, never a number.
+	var format int
+	if json.Unmarshal(raw["format"], &format) != nil {
+		var legacy map[string]string
+		if err := json.Unmarshal(plain, &legacy); err != nil {
+			return nil, err
+		}
+		entries := make(map[string]Entry, len(legacy))
+		for key, value := range legacy {
+			entries[key] = Entry{Value: []byte(value)}
+		}
+		return entries, nil
+	}
+
+	if format != dataFormat {
+		return nil, fmt.Errorf("unsupported vault data format %d", format)
+	}
+	var data vaultData
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return nil, err
+	}
+	if data.Entries == nil {
+		data.Entries = make(map[string]Entry)
+	}
+	return data.Entries, nil
+}
+
+func encodeEntries(entries map[string]Entry) ([]byte, error) {
+	return json.Marshal(vaultData{Format: dataFormat, Entries: entries})
+}
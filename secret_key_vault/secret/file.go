@@ -0,0 +1,202 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"secret_key_vault/encrypt"
+)
+
+// DefaultBackups is how many previous versions of a vault NewFileVault keeps.
+const DefaultBackups = 5
+
+// Backup is a previous version of a vault, kept next to it as <path>.N with
+// 1 the newest.
+type Backup struct {
+	N       int
+	Path    string
+	ModTime time.Time
+}
+
+// lock takes an exclusive advisory lock on <path>.lock, so that concurrent
+// writers do not lose each other's updates. Call the returned function to
+// release it.
+func (fv *FileVault) lock() (func(), error) {
+	f, err := os.OpenFile(fv.Path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot lock vault: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// replace makes data the new vault contents. The current file becomes backup
+// 1 and older backups move up by one, dropping those beyond fv.Backups. A
+// legacy vault is converted before it is kept, so the weak format does not
+// stay on disk after migration. Every file is replaced with an fsynced temp
+// file and a rename, so a crash leaves either the old or the new version,
+// never a mix. The caller holds the lock.
+func (fv *FileVault) replace(data []byte) error {
+	if fv.Backups > 0 {
+		current, err := os.ReadFile(fv.Path)
+		switch {
+		case err == nil:
+			if current, err = fv.upgrade(current); err != nil {
+				return err
+			}
+			if err := fv.rotateBackups(); err != nil {
+				return err
+			}
+			if err := writeFileAtomic(fv.backupPath(1), current); err != nil {
+				return err
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+	}
+	return writeFileAtomic(fv.Path, data)
+}
+
+func (fv *FileVault) rotateBackups() error {
+	for n := fv.Backups; n >= 1; n-- {
+		from := fv.backupPath(n)
+		var err error
+		if n == fv.Backups {
+			err = os.Remove(from)
+		} else {
+			err = os.Rename(from, fv.backupPath(n+1))
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fv *FileVault) backupPath(n int) string {
+	return fv.Path + "." + strconv.Itoa(n)
+}
+
+// ListBackups lists the previous versions of the vault, newest first.
+func (fv *FileVault) ListBackups() ([]Backup, error) {
+	matches, err := filepath.Glob(fv.Path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, match := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(match, fv.Path+"."))
+		if err != nil || n < 1 {
+			continue
+		}
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{N: n, Path: match, ModTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].N < backups[j].N })
+	return backups, nil
+}
+
+// Restore rolls the vault back to backup n. The backup must open with Key.
+// The version being replaced becomes backup 1, so a restore can itself be
+// undone with Restore(1).
+func (fv *FileVault) Restore(n int) error {
+	unlock, err := fv.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(fv.backupPath(n))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no backup %d", n)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := fv.decodeSecrets(data); err != nil {
+		return fmt.Errorf("backup %d: %w", n, err)
+	}
+	if data, err = fv.upgrade(data); err != nil {
+		return fmt.Errorf("backup %d: %w", n, err)
+	}
+	return fv.replace(data)
+}
+
+// upgrade returns data in the current format. Vaults in the legacy format,
+// which has no integrity check and a weak key, are opened with Key and
+// sealed again for it alone; anything else is returned unchanged.
+func (fv *FileVault) upgrade(data []byte) ([]byte, error) {
+	if !encrypt.IsLegacy(string(data)) {
+		return data, nil
+	}
+	plain, err := encrypt.Decrypt(string(data), fv.Key)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := decodeEntries([]byte(plain))
+	if err != nil {
+		return nil, encrypt.ErrDecrypt
+	}
+	jsonData, err := encodeEntries(entries)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := encrypt.Encrypt(string(jsonData), fv.Key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(sealed), nil
+}
+
+// writeFileAtomic replaces path with data through a synced temp file in the
+// same directory.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Make the rename itself durable.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
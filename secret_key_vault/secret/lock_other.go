@@ -0,0 +1,16 @@
+//go:build !unix
+
+package secret
+
+import "os"
+
+// Advisory locking is only implemented for Unix; elsewhere concurrent
+// writers are not serialized, but each write is still atomic.
+
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
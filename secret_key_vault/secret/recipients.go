@@ -0,0 +1,127 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+
+	"secret_key_vault/encrypt"
+)
+
+// Rekey re-encrypts the vault and its backups under newKey in place of Key,
+// so the old key no longer opens any version kept on disk. Other recipients
+// keep their access. It reports whether the data key could be rotated as
+// well; see encrypt.Keyring.Rekey. It fails without changing anything if a
+// backup does not open with Key.
+func (fv *FileVault) Rekey(newKey string) (rotated bool, err error) {
+	unlock, err := fv.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	secrets, err := fv.readSecrets()
+	if err != nil {
+		return false, err
+	}
+	if fv.keyring == nil {
+		if fv.keyring, err = encrypt.NewKeyring(fv.Key); err != nil {
+			return false, err
+		}
+	}
+	backups, err := fv.ListBackups()
+	if err != nil {
+		return false, err
+	}
+	contents := make([]map[string]Entry, len(backups))
+	for i, b := range backups {
+		if contents[i], err = fv.openBackup(b); err != nil {
+			return false, err
+		}
+	}
+
+	if rotated, err = fv.keyring.Rekey(newKey); err != nil {
+		return false, err
+	}
+	fv.Key = newKey
+
+	// The contents are unchanged, so the live vault is rewritten without
+	// adding a backup of the old version.
+	if err := fv.writeSealed(fv.Path, secrets); err != nil {
+		return false, err
+	}
+	for i, b := range backups {
+		if err := fv.writeSealed(b.Path, contents[i]); err != nil {
+			return false, fmt.Errorf("backup %d: %w", b.N, err)
+		}
+	}
+	return rotated, nil
+}
+
+// openBackup decrypts a backup with Key without touching fv.keyring.
+func (fv *FileVault) openBackup(b Backup) (map[string]Entry, error) {
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return nil, err
+	}
+	plain, _, err := encrypt.Open(string(data), fv.Key)
+	if err == nil {
+		var entries map[string]Entry
+		if entries, err = decodeContents(data, plain); err == nil {
+			return entries, nil
+		}
+	}
+	return nil, fmt.Errorf("backup %d: %w; delete %s to rekey", b.N, err, b.Path)
+}
+
+// writeSealed seals secrets with fv.keyring and writes them to path.
+func (fv *FileVault) writeSealed(path string, secrets map[string]Entry) error {
+	jsonData, err := encodeEntries(secrets)
+	if err != nil {
+		return err
+	}
+	encrypted, err := fv.keyring.Seal(string(jsonData))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(encrypted))
+}
+
+// Recipients lists the passphrases and public keys that can open the vault.
+func (fv *FileVault) Recipients() ([]encrypt.Recipient, error) {
+	if _, err := fv.readSecrets(); err != nil {
+		return nil, err
+	}
+	if fv.keyring == nil {
+		keyring, err := encrypt.NewKeyring(fv.Key)
+		if err != nil {
+			return nil, err
+		}
+		return keyring.Recipients(), nil
+	}
+	return fv.keyring.Recipients(), nil
+}
+
+// AddPassphrase lets another passphrase open the vault.
+func (fv *FileVault) AddPassphrase(label, passphrase string) error {
+	return fv.update(func(secrets map[string]Entry) error {
+		return fv.keyring.AddPassphrase(label, passphrase)
+	})
+}
+
+// AddPublicKey lets the holder of an identity open the vault.
+func (fv *FileVault) AddPublicKey(label, publicKey string) error {
+	return fv.update(func(secrets map[string]Entry) error {
+		return fv.keyring.AddPublicKey(label, publicKey)
+	})
+}
+
+// RemoveRecipient revokes a recipient's access to future versions of the
+// vault. It reports whether the data key could be rotated as well; if not,
+// a removed recipient who saved the old data key could still read them.
+func (fv *FileVault) RemoveRecipient(label string) (rotated bool, err error) {
+	err = fv.update(func(secrets map[string]Entry) error {
+		rotated, err = fv.keyring.Remove(label)
+		return err
+	})
+	return rotated, err
+}
@@ -1,68 +1,246 @@
 package secret
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path"
+	"sort"
+	"time"
 
 	"secret_key_vault/encrypt"
 )
 
+var (
+	ErrNotFound = errors.New("key not found")
+	ErrExists   = errors.New("key already exists")
+	ErrBinary   = errors.New("value is binary; use get-file")
+)
+
 type FileVault struct {
 	Key  string
 	Path string
+
+	// Backups is how many previous versions of the file to keep.
+	Backups int
+
+	// keyring is set by reading the vault, so that writing it back keeps
+	// the recipients it was shared with.
+	keyring *encrypt.Keyring
+}
+
+// Secret is a key and its entry, as returned by List.
+type Secret struct {
+	Key string
+	Entry
 }
 
 func NewFileVault(key, path string) *FileVault {
-	return &FileVault{Key: key, Path: path}
+	return &FileVault{Key: key, Path: path, Backups: DefaultBackups}
+}
+
+// Verify checks that Key opens the vault. A vault that does not exist yet
+// accepts any key.
+func (fv *FileVault) Verify() error {
+	_, err := fv.readSecrets()
+	return err
+}
+
+// IsNew reports whether the vault file has not been created yet.
+func (fv *FileVault) IsNew() bool {
+	_, err := os.Stat(fv.Path)
+	return errors.Is(err, os.ErrNotExist)
 }
 
-func (fv *FileVault) readSecrets() (map[string]string, error) {
+func (fv *FileVault) readSecrets() (map[string]Entry, error) {
 	data, err := os.ReadFile(fv.Path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return make(map[string]string), nil // file not found = empty map
+			fv.keyring = nil
+			return make(map[string]Entry), nil // file not found = empty map
 		}
 		return nil, err
 	}
-	decrypted, err := encrypt.Decrypt(string(data), fv.Key)
+	return fv.decodeSecrets(data)
+}
+
+func (fv *FileVault) decodeSecrets(data []byte) (map[string]Entry, error) {
+	decrypted, keyring, err := encrypt.Open(string(data), fv.Key)
 	if err != nil {
 		return nil, err
 	}
-	var secrets map[string]string
-	err = json.Unmarshal([]byte(decrypted), &secrets)
-	return secrets, err
+	fv.keyring = keyring
+	return decodeContents(data, decrypted)
 }
 
-func (fv *FileVault) writeSecrets(secrets map[string]string) error {
-	jsonData, err := json.Marshal(secrets)
+// decodeContents decodes the decrypted contents of the vault file data.
+func decodeContents(data []byte, decrypted string) (map[string]Entry, error) {
+	secrets, err := decodeEntries([]byte(decrypted))
+	if err != nil {
+		// Legacy vaults are not authenticated, so a wrong key shows up as
+		// garbage rather than as a decryption error.
+		if encrypt.IsLegacy(string(data)) {
+			return nil, encrypt.ErrDecrypt
+		}
+		return nil, fmt.Errorf("cannot read vault contents: %w", err)
+	}
+	return secrets, nil
+}
+
+// writeSecrets always writes the current format, so the first write to a
+// legacy vault migrates it. The keyring comes from the preceding read.
+func (fv *FileVault) writeSecrets(secrets map[string]Entry) error {
+	jsonData, err := encodeEntries(secrets)
 	if err != nil {
 		return err
 	}
-	encrypted, err := encrypt.Encrypt(string(jsonData), fv.Key)
+	encrypted, err := fv.keyring.Seal(string(jsonData))
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(fv.Path, []byte(encrypted), 0600)
+	return fv.replace([]byte(encrypted))
 }
 
-func (fv *FileVault) Set(key, value string) error {
+// update runs fn on the secrets and writes them back, holding the vault lock
+// for the whole read-modify-write cycle.
+func (fv *FileVault) update(fn func(secrets map[string]Entry) error) error {
+	unlock, err := fv.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	secrets, err := fv.readSecrets()
 	if err != nil {
 		return err
 	}
-	secrets[key] = value
+	if fv.keyring == nil {
+		if fv.keyring, err = encrypt.NewKeyring(fv.Key); err != nil {
+			return err
+		}
+	}
+	if err := fn(secrets); err != nil {
+		return err
+	}
 	return fv.writeSecrets(secrets)
 }
 
+func (fv *FileVault) Set(key, value string) error {
+	return fv.Put(key, []byte(value), false, Meta{})
+}
+
+// SetFile stores the contents of a file, which may be binary, under key.
+func (fv *FileVault) SetFile(key, filename string, meta Meta) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return fv.Put(key, data, true, meta)
+}
+
+// Put stores value under key and updates its metadata. The entry keeps its
+// creation time and any metadata that meta leaves unchanged.
+func (fv *FileVault) Put(key string, value []byte, binary bool, meta Meta) error {
+	return fv.update(func(secrets map[string]Entry) error {
+		now := time.Now().UTC()
+		entry, ok := secrets[key]
+		if !ok || entry.Created.IsZero() {
+			entry.Created = now
+		}
+		entry.Value = value
+		entry.Binary = binary
+		entry.Updated = now
+		meta.apply(&entry)
+		secrets[key] = entry
+		return nil
+	})
+}
+
+// Get returns a text value. Binary values stored with SetFile fail with
+// ErrBinary; use Entry for them.
 func (fv *FileVault) Get(key string) (string, error) {
-	secrets, err := fv.readSecrets()
+	entry, err := fv.Entry(key)
 	if err != nil {
 		return "", err
 	}
-	val, ok := secrets[key]
+	if entry.Binary {
+		return "", ErrBinary
+	}
+	return string(entry.Value), nil
+}
+
+// Entry returns the value of key with its metadata.
+func (fv *FileVault) Entry(key string) (Entry, error) {
+	secrets, err := fv.readSecrets()
+	if err != nil {
+		return Entry{}, err
+	}
+	entry, ok := secrets[key]
 	if !ok {
-		return "", errors.New("key not found")
+		return Entry{}, ErrNotFound
+	}
+	return entry, nil
+}
+
+// List returns the secrets whose keys match the glob pattern (see path.Match),
+// sorted by key. An empty pattern matches every key.
+func (fv *FileVault) List(pattern string) ([]Secret, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	secrets, err := fv.readSecrets()
+	if err != nil {
+		return nil, err
 	}
-	return val, nil
-}
\ No newline at end of file
+
+	var list []Secret
+	for key, entry := range secrets {
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, key); !ok {
+				continue
+			}
+		}
+		list = append(list, Secret{Key: key, Entry: entry})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list, nil
+}
+
+func (fv *FileVault) Delete(key string) error {
+	return fv.update(func(secrets map[string]Entry) error {
+		if _, ok := secrets[key]; !ok {
+			return ErrNotFound
+		}
+		delete(secrets, key)
+		return nil
+	})
+}
+
+// Rename moves the entry of oldKey to newKey. It fails with ErrExists rather
+// than overwrite an existing newKey.
+func (fv *FileVault) Rename(oldKey, newKey string) error {
+	return fv.update(func(secrets map[string]Entry) error {
+		entry, ok := secrets[oldKey]
+		if !ok {
+			return ErrNotFound
+		}
+		if oldKey == newKey {
+			return nil
+		}
+		if _, ok := secrets[newKey]; ok {
+			return ErrExists
+		}
+		delete(secrets, oldKey)
+		secrets[newKey] = entry
+		return nil
+	})
+}
+
+func (fv *FileVault) Exists(key string) (bool, error) {
+	secrets, err := fv.readSecrets()
+	if err != nil {
+		return false, err
+	}
+	_, ok := secrets[key]
+	return ok, nil
+}